@@ -0,0 +1,77 @@
+package goTx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Event struct {
+	ID            string
+	Type          string
+	CorrelationID string
+	Payload       []byte
+	Error         string
+	CreatedAt     time.Time
+}
+
+type EventHandler func(ctx context.Context, event Event) error
+
+type MessageBus interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(eventType string, handler EventHandler) (unsubscribe func(), err error)
+}
+
+// MemoryBus is an in-process MessageBus. Events are delivered synchronously:
+// Publish calls the handlers subscribed to the event type in subscription order
+// and returns their errors.
+type MemoryBus struct {
+	mu       sync.Mutex
+	handlers map[string][]subscription
+	nextID   int
+}
+
+type subscription struct {
+	id      int
+	handler EventHandler
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: make(map[string][]subscription)}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	subs := append([]subscription(nil), b.handlers[event.Type]...)
+	b.mu.Unlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs...)
+}
+
+func (b *MemoryBus) Subscribe(eventType string, handler EventHandler) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.handlers[eventType] = append(b.handlers[eventType], subscription{id: id, handler: handler})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		subs := b.handlers[eventType]
+		for i, sub := range subs {
+			if sub.id == id {
+				b.handlers[eventType] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}, nil
+}
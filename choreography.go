@@ -0,0 +1,242 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	EventStarted            = "started"
+	EventSucceeded          = "succeeded"
+	EventFailed             = "failed"
+	EventCompensated        = "compensated"
+	EventCompensationFailed = "compensation-failed"
+	EventCompleted          = "completed"
+	EventAborted            = "aborted"
+)
+
+var ErrUnknownParticipant = errors.New("unknown participant")
+
+// Participant is a step of a choreographed saga. Handle receives the event that
+// triggers it, the start event of the saga or the success event of the previous
+// participant, and returns the payload of its own success event. Compensate
+// receives the failure or compensation event of the next participant.
+type Participant struct {
+	Name       string
+	Handle     func(ctx context.Context, event Event) ([]byte, error)
+	Compensate func(ctx context.Context, event Event) error
+}
+
+// Choreography runs a saga without an orchestrator: every participant subscribes
+// to the events of its neighbours on a MessageBus and emits its own. All events
+// of one run share the correlation ID returned by Start.
+//
+// The event types are "<saga>.started", "<saga>.completed" and "<saga>.aborted"
+// for the saga and "<saga>.<participant>.<event>" for the participants, see
+// ChoreographyEventType.
+type Choreography struct {
+	name         string
+	bus          MessageBus
+	participants []Participant
+
+	mu     sync.Mutex
+	unsubs []func()
+}
+
+func NewChoreography(name string, bus MessageBus, participants ...Participant) *Choreography {
+	return &Choreography{name: name, bus: bus, participants: participants}
+}
+
+func ChoreographyEventType(saga, participant, event string) string {
+	if participant == "" {
+		return saga + "." + event
+	}
+	return saga + "." + participant + "." + event
+}
+
+// Subscribe subscribes the participants with the given names, or all
+// participants if no name is given, to the bus. Participants hosted by other
+// processes subscribe through a Choreography with the same participant order.
+func (c *Choreography) Subscribe(names ...string) error {
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	for i := range c.participants {
+		if len(names) > 0 && !selected[c.participants[i].Name] {
+			continue
+		}
+		delete(selected, c.participants[i].Name)
+
+		if err := c.subscribeParticipant(i); err != nil {
+			c.Close()
+			return err
+		}
+	}
+
+	for name := range selected {
+		c.Close()
+		return fmt.Errorf("%w: %q", ErrUnknownParticipant, name)
+	}
+	return nil
+}
+
+func (c *Choreography) subscribeParticipant(i int) error {
+	trigger := c.eventType("", EventStarted)
+	if i > 0 {
+		trigger = c.eventType(c.participants[i-1].Name, EventSucceeded)
+	}
+	if err := c.subscribe(trigger, func(ctx context.Context, event Event) error {
+		return c.handle(ctx, i, event)
+	}); err != nil {
+		return err
+	}
+
+	if i == len(c.participants)-1 {
+		return nil
+	}
+	next := c.participants[i+1].Name
+	for _, kind := range []string{EventFailed, EventCompensated} {
+		if err := c.subscribe(c.eventType(next, kind), func(ctx context.Context, event Event) error {
+			return c.compensate(ctx, i, event)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Choreography) subscribe(eventType string, handler EventHandler) error {
+	unsub, err := c.bus.Subscribe(eventType, handler)
+	if err != nil {
+		return fmt.Errorf("subscribe to %q: %w", eventType, err)
+	}
+
+	c.mu.Lock()
+	c.unsubs = append(c.unsubs, unsub)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Choreography) handle(ctx context.Context, i int, trigger Event) error {
+	p := c.participants[i]
+	var payload []byte
+	err := safeStep(func(ctx context.Context) (err error) {
+		payload, err = p.Handle(ctx, trigger)
+		return err
+	})(ctx)
+	if err != nil {
+		if perr := c.publish(ctx, trigger.CorrelationID, c.eventType(p.Name, EventFailed), nil, err); perr != nil {
+			return perr
+		}
+		if i == 0 {
+			return c.publish(ctx, trigger.CorrelationID, c.eventType("", EventAborted), nil, err)
+		}
+		return nil
+	}
+
+	if err := c.publish(ctx, trigger.CorrelationID, c.eventType(p.Name, EventSucceeded), payload, nil); err != nil {
+		return err
+	}
+	if i == len(c.participants)-1 {
+		return c.publish(ctx, trigger.CorrelationID, c.eventType("", EventCompleted), payload, nil)
+	}
+	return nil
+}
+
+func (c *Choreography) compensate(ctx context.Context, i int, trigger Event) error {
+	p := c.participants[i]
+	if p.Compensate != nil {
+		compensate := safeStep(func(ctx context.Context) error { return p.Compensate(ctx, trigger) })
+		if err := compensate(ctx); err != nil {
+			return c.publish(ctx, trigger.CorrelationID, c.eventType(p.Name, EventCompensationFailed), nil, err)
+		}
+	}
+
+	if err := c.publish(ctx, trigger.CorrelationID, c.eventType(p.Name, EventCompensated), nil, nil); err != nil {
+		return err
+	}
+	if i == 0 {
+		return c.publish(ctx, trigger.CorrelationID, c.eventType("", EventAborted), nil, nil)
+	}
+	return nil
+}
+
+// Start publishes the start event of a new run of the saga and returns its
+// correlation ID.
+func (c *Choreography) Start(ctx context.Context, payload []byte) (string, error) {
+	correlationID := newID()
+	return correlationID, c.publish(ctx, correlationID, c.eventType("", EventStarted), payload, nil)
+}
+
+// StartAndWait starts a run of the saga and waits for its completed or aborted
+// event, or for the compensation-failed event of a participant.
+func (c *Choreography) StartAndWait(ctx context.Context, payload []byte) (Event, error) {
+	correlationID := newID()
+	done := make(chan Event, 1)
+	await := func(_ context.Context, event Event) error {
+		if event.CorrelationID == correlationID {
+			select {
+			case done <- event:
+			default:
+			}
+		}
+		return nil
+	}
+
+	eventTypes := []string{c.eventType("", EventCompleted), c.eventType("", EventAborted)}
+	for _, p := range c.participants {
+		eventTypes = append(eventTypes, c.eventType(p.Name, EventCompensationFailed))
+	}
+	for _, eventType := range eventTypes {
+		unsub, err := c.bus.Subscribe(eventType, await)
+		if err != nil {
+			return Event{}, err
+		}
+		defer unsub()
+	}
+
+	if err := c.publish(ctx, correlationID, c.eventType("", EventStarted), payload, nil); err != nil {
+		return Event{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return Event{}, ctx.Err()
+	case event := <-done:
+		return event, nil
+	}
+}
+
+// Close unsubscribes all participants subscribed through c.
+func (c *Choreography) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, unsub := range c.unsubs {
+		unsub()
+	}
+	c.unsubs = nil
+}
+
+func (c *Choreography) eventType(participant, event string) string {
+	return ChoreographyEventType(c.name, participant, event)
+}
+
+func (c *Choreography) publish(ctx context.Context, correlationID, eventType string, payload []byte, err error) error {
+	event := Event{
+		ID:            newID(),
+		Type:          eventType,
+		CorrelationID: correlationID,
+		Payload:       payload,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return c.bus.Publish(ctx, event)
+}
@@ -0,0 +1,151 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestChoreography_StartAndWait(t *testing.T) {
+	errHandle := errors.New("handle error")
+	tests := []struct {
+		name      string
+		failing   []string
+		wantEvent string
+		wantLog   []string
+	}{
+		{
+			name:      "happypath",
+			wantEvent: "order.completed",
+			wantLog:   []string{"reserve <- order", "charge <- reserve", "ship <- charge"},
+		},
+		{
+			name:      "last-fails",
+			failing:   []string{"ship"},
+			wantEvent: "order.aborted",
+			wantLog: []string{
+				"reserve <- order", "charge <- reserve", "ship <- charge",
+				"compensate charge <- order.ship.failed", "compensate reserve <- order.charge.compensated",
+			},
+		},
+		{
+			name:      "first-fails",
+			failing:   []string{"reserve"},
+			wantEvent: "order.aborted",
+			wantLog:   []string{"reserve <- order"},
+		},
+		{
+			name:      "compensation-fails",
+			failing:   []string{"ship", "compensate charge"},
+			wantEvent: "order.charge.compensation-failed",
+			wantLog: []string{
+				"reserve <- order", "charge <- reserve", "ship <- charge",
+				"compensate charge <- order.ship.failed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				failing := make(map[string]bool)
+				for _, name := range tt.failing {
+					failing[name] = true
+				}
+				log := &stepLog{}
+				correlationIDs := make(map[string]bool)
+				participant := func(name string) Participant {
+					return Participant{
+						Name: name,
+						Handle: func(_ context.Context, event Event) ([]byte, error) {
+							log.add(name + " <- " + string(event.Payload))
+							correlationIDs[event.CorrelationID] = true
+							if failing[name] {
+								return nil, errHandle
+							}
+							return []byte(name), nil
+						},
+						Compensate: func(_ context.Context, event Event) error {
+							log.add("compensate " + name + " <- " + event.Type)
+							correlationIDs[event.CorrelationID] = true
+							if failing["compensate "+name] {
+								return errHandle
+							}
+							return nil
+						},
+					}
+				}
+
+				bus := NewMemoryBus()
+				choreography := NewChoreography("order", bus, participant("reserve"), participant("charge"), participant("ship"))
+				if err := choreography.Subscribe(); err != nil {
+					t.Fatal(err)
+				}
+				defer choreography.Close()
+
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				event, err := choreography.StartAndWait(ctx, []byte("order"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if event.Type != tt.wantEvent {
+					t.Errorf("StartAndWait() event = %q, want %q", event.Type, tt.wantEvent)
+				}
+				if log.String() != fmt.Sprint(tt.wantLog) {
+					t.Errorf("log = %v, want %v", log, tt.wantLog)
+				}
+				if len(correlationIDs) != 1 || !correlationIDs[event.CorrelationID] {
+					t.Errorf("correlation IDs = %v, want only %q", correlationIDs, event.CorrelationID)
+				}
+			},
+		)
+	}
+}
+
+func TestChoreography_Subscribe(t *testing.T) {
+	bus := NewMemoryBus()
+	var handled []string
+	participant := func(name string) Participant {
+		return Participant{
+			Name: name,
+			Handle: func(context.Context, Event) ([]byte, error) {
+				handled = append(handled, name)
+				return nil, nil
+			},
+		}
+	}
+	participants := []Participant{participant("a"), participant("b")}
+
+	// Each participant subscribes through its own Choreography, as it would
+	// from its own service.
+	first := NewChoreography("split", bus, participants...)
+	second := NewChoreography("split", bus, participants...)
+	if err := first.Subscribe("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Subscribe("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := first.StartAndWait(context.Background(), nil)
+	if err != nil || event.Type != "split.completed" {
+		t.Errorf("StartAndWait() = %q, %v, want split.completed", event.Type, err)
+	}
+	if fmt.Sprint(handled) != "[a b]" {
+		t.Errorf("handled = %v, want [a b]", handled)
+	}
+
+	second.Close()
+	if _, err := first.Start(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(handled) != "[a b a]" {
+		t.Errorf("handled after Close() = %v, want [a b a]", handled)
+	}
+
+	if err := NewChoreography("split", bus, participants...).Subscribe("c"); !errors.Is(err, ErrUnknownParticipant) {
+		t.Errorf("Subscribe(c) error = %v, want %v", err, ErrUnknownParticipant)
+	}
+}
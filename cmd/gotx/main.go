@@ -0,0 +1,300 @@
+// Command gotx inspects the saga executions and dead letters saved in a goTx
+// FileStore and queues commands to resume, compensate or skip a step of stuck
+// and suspended executions.
+//
+// Usage:
+//
+//	gotx [-dir store] list [-saga name] [-state state] [-stuck duration]
+//	gotx [-dir store] show <execution id>
+//	gotx [-dir store] resume <execution id>
+//	gotx [-dir store] compensate <execution id>
+//	gotx [-dir store] skip <execution id> <step>
+//	gotx [-dir store] deadletters
+//	gotx [-dir store] discard <dead letter id>
+//
+// The store directory defaults to $GOTX_STORE. Commands are applied by the
+// process running the sagas, with goTx.HandleCommands. A dead-lettered
+// execution is retried by queueing its compensation.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+var errUsage = errors.New("usage: gotx [-dir store] list|show|resume|compensate|skip|deadletters|discard [arguments]")
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, "gotx:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("gotx", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dir := fs.String("dir", os.Getenv("GOTX_STORE"), "saga store directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dir == "" || fs.NArg() == 0 {
+		return errUsage
+	}
+
+	store, err := goTx.NewFileStore(*dir)
+	if err != nil {
+		return err
+	}
+
+	cmd, args := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		return list(ctx, store, args, out, now)
+	case "show":
+		return show(ctx, store, args, out)
+	case "resume":
+		return queue(ctx, store, args, out, goTx.CommandResume)
+	case "compensate":
+		return queue(ctx, store, args, out, goTx.CommandCompensate)
+	case "skip":
+		return queue(ctx, store, args, out, goTx.CommandSkip)
+	case "deadletters":
+		return deadLetters(ctx, store, out)
+	case "discard":
+		return discard(ctx, store, args, out)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func list(ctx context.Context, store *goTx.FileStore, args []string, out io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	saga := fs.String("saga", "", "only list executions of this saga")
+	state := fs.String("state", "", "only list executions in this state")
+	stuck := fs.Duration("stuck", 0, "only list running executions without progress for this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var wantState goTx.ExecutionState
+	if *state != "" {
+		if err := wantState.UnmarshalText([]byte(*state)); err != nil {
+			return err
+		}
+	}
+
+	reports, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSAGA\tSTATE\tSTARTED\tLAST ACTIVITY")
+	for _, r := range reports {
+		if *saga != "" && r.Saga != *saga {
+			continue
+		}
+		if *state != "" && r.State != wantState {
+			continue
+		}
+		last := lastActivity(r)
+		if *stuck > 0 && (r.State != goTx.ExecutionRunning || now.Sub(last) < *stuck) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Saga, r.State, formatTime(r.StartedAt), formatTime(last))
+	}
+	return w.Flush()
+}
+
+func show(ctx context.Context, store *goTx.FileStore, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	r, err := store.Load(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "execution %s of saga %s: %s\n", r.ID, r.Saga, r.State)
+	if r.SuspendReason != "" {
+		fmt.Fprintf(out, "suspended: %s\n", r.SuspendReason)
+	}
+	if r.Err != nil {
+		fmt.Fprintf(out, "error: %v\n", r.Err)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, ev := range timeline(r, "") {
+		offset := ""
+		if !r.StartedAt.IsZero() {
+			offset = "+" + ev.at.Sub(r.StartedAt).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatTime(ev.at), offset, ev.step, ev.what)
+	}
+	return w.Flush()
+}
+
+func queue(ctx context.Context, store *goTx.FileStore, args []string, out io.Writer, action goTx.CommandAction) error {
+	cmd := goTx.Command{Action: action}
+	switch {
+	case action == goTx.CommandSkip && len(args) == 2:
+		cmd.Step = args[1]
+	case action == goTx.CommandSkip || len(args) != 1:
+		return errUsage
+	}
+
+	r, err := store.Load(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	cmd.ExecutionID = r.ID
+
+	switch {
+	case action != goTx.CommandCompensate && r.State != goTx.ExecutionRunning && r.State != goTx.ExecutionSuspended:
+		return fmt.Errorf("cannot %s %s execution %s", action, r.State, r.ID)
+	case action == goTx.CommandCompensate && (r.State == goTx.ExecutionPending || r.State == goTx.ExecutionCompensated):
+		return fmt.Errorf("cannot compensate %s execution %s", r.State, r.ID)
+	case action == goTx.CommandSkip:
+		if _, ok := r.Step(cmd.Step); !ok {
+			return fmt.Errorf("%w: %q", goTx.ErrUnknownStep, cmd.Step)
+		}
+	}
+
+	if err := store.AddCommand(ctx, cmd); err != nil {
+		return err
+	}
+	if cmd.Step != "" {
+		fmt.Fprintf(out, "queued %s of step %s of execution %s\n", action, cmd.Step, r.ID)
+	} else {
+		fmt.Fprintf(out, "queued %s of execution %s\n", action, r.ID)
+	}
+	return nil
+}
+
+func deadLetters(ctx context.Context, store *goTx.FileStore, out io.Writer) error {
+	deadLetters, err := store.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSAGA\tSTEP\tCREATED\tATTEMPTS\tERROR")
+	for _, dl := range deadLetters {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%v\n", dl.ID, dl.Saga, dl.Step, formatTime(dl.CreatedAt), dl.Attempts, dl.Err)
+	}
+	return w.Flush()
+}
+
+func discard(ctx context.Context, store *goTx.FileStore, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	deadLetters, err := store.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dl := range deadLetters {
+		if dl.ID == args[0] {
+			if err := store.DeleteDeadLetter(ctx, dl.ID); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "discarded dead letter %s\n", dl.ID)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown dead letter %q", args[0])
+}
+
+type event struct {
+	at   time.Time
+	step string
+	what string
+}
+
+// timeline returns the events of an execution and of its nested executions in
+// the order they happened.
+func timeline(r *goTx.ExecutionReport, prefix string) []event {
+	var events []event
+	for _, step := range r.Steps {
+		name := prefix + step.Name
+		if !step.StartedAt.IsZero() {
+			events = append(events, event{step.StartedAt, name, "started"})
+			switch step.Status {
+			case goTx.StepSucceeded, goTx.StepCompensating, goTx.StepCompensated, goTx.StepCompensationFailed, goTx.StepSkipped:
+				events = append(events, event{step.StartedAt.Add(step.Duration), name, outcome("succeeded", step.Attempts, step.Replayed, nil)})
+			case goTx.StepFailed:
+				events = append(events, event{step.StartedAt.Add(step.Duration), name, outcome("failed", step.Attempts, step.Replayed, step.Err)})
+			}
+		}
+		if !step.CompensationStartedAt.IsZero() {
+			events = append(events, event{step.CompensationStartedAt, name, "compensation started"})
+			end := step.CompensationStartedAt.Add(step.CompensationDuration)
+			switch step.Status {
+			case goTx.StepCompensated:
+				events = append(events, event{end, name, outcome("compensated", step.CompensationAttempts, step.CompensationReplayed, nil)})
+			case goTx.StepCompensationFailed:
+				events = append(events, event{end, name, outcome("compensation failed", step.CompensationAttempts, step.CompensationReplayed, step.CompensationErr)})
+			}
+		}
+		if step.Child != nil {
+			events = append(events, timeline(step.Child, name+"/")...)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	return events
+}
+
+func outcome(what string, attempts int, replayed bool, err error) string {
+	var details []string
+	if attempts > 1 {
+		details = append(details, fmt.Sprintf("%d attempts", attempts))
+	}
+	if replayed {
+		details = append(details, "replayed")
+	}
+	if len(details) > 0 {
+		what += " (" + strings.Join(details, ", ") + ")"
+	}
+	if err != nil {
+		what += ": " + err.Error()
+	}
+	return what
+}
+
+// lastActivity returns the last time the execution or one of its steps started
+// or finished.
+func lastActivity(r *goTx.ExecutionReport) time.Time {
+	last := r.StartedAt
+	if r.FinishedAt.After(last) {
+		last = r.FinishedAt
+	}
+	for _, ev := range timeline(r, "") {
+		if ev.at.After(last) {
+			last = ev.at
+		}
+	}
+	return last
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := goTx.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+	reports := []*goTx.ExecutionReport{
+		{
+			ID:        "stuck",
+			Saga:      "order",
+			State:     goTx.ExecutionRunning,
+			StartedAt: start,
+			Steps: []goTx.StepReport{
+				{Name: "charge", Status: goTx.StepSucceeded, StartedAt: start, Attempts: 1, Duration: time.Second},
+				{Name: "ship", Status: goTx.StepRunning, StartedAt: start.Add(time.Second), Attempts: 1},
+			},
+		},
+		{
+			ID:         "failed",
+			Saga:       "order",
+			State:      goTx.ExecutionFailed,
+			Err:        errors.New(`step "ship": out of stock`),
+			StartedAt:  start.Add(time.Minute),
+			FinishedAt: start.Add(time.Minute + 5*time.Second),
+			Steps: []goTx.StepReport{
+				{
+					Name: "charge", Status: goTx.StepCompensationFailed, StartedAt: start.Add(time.Minute), Attempts: 1, Duration: time.Second,
+					CompensationStartedAt: start.Add(time.Minute + 3*time.Second), CompensationAttempts: 1, CompensationDuration: time.Second,
+					CompensationErr: errors.New("refund rejected"),
+				},
+				{Name: "ship", Status: goTx.StepFailed, StartedAt: start.Add(time.Minute + time.Second), Attempts: 3, Duration: 2 * time.Second, Err: errors.New("out of stock")},
+			},
+		},
+		{
+			ID:         "done",
+			Saga:       "refund",
+			State:      goTx.ExecutionSucceeded,
+			StartedAt:  now.Add(-time.Minute),
+			FinishedAt: now.Add(-time.Minute),
+		},
+	}
+	for _, r := range reports {
+		if err := store.Save(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = store.AddDeadLetter(ctx, goTx.DeadLetter{
+		ID:          "failed",
+		ExecutionID: "failed",
+		Saga:        "order",
+		Step:        "charge",
+		Err:         errors.New("refund rejected"),
+		Attempts:    1,
+		CreatedAt:   start.Add(time.Minute + 4*time.Second),
+		Report:      reports[1],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name: "list",
+			args: []string{"list"},
+			want: []string{"stuck", "failed", "done"},
+		},
+		{
+			name:    "list failed",
+			args:    []string{"list", "-state", "failed"},
+			want:    []string{"failed  order  failed"},
+			notWant: []string{"stuck", "done"},
+		},
+		{
+			name:    "list stuck",
+			args:    []string{"list", "-stuck", "30m"},
+			want:    []string{"stuck", "2024-05-01T12:00:01Z"},
+			notWant: []string{"failed", "done"},
+		},
+		{
+			name:    "list saga",
+			args:    []string{"list", "-saga", "refund"},
+			want:    []string{"done"},
+			notWant: []string{"stuck", "failed"},
+		},
+		{
+			name:    "list unknown state",
+			args:    []string{"list", "-state", "lost"},
+			wantErr: true,
+		},
+		{
+			name: "show",
+			args: []string{"show", "failed"},
+			want: []string{
+				"execution failed of saga order: failed",
+				`error: step "ship": out of stock`,
+				"2024-05-01T12:01:00Z  +0s  charge  started",
+				"2024-05-01T12:01:03Z  +3s  charge  compensation started",
+				"2024-05-01T12:01:03Z  +3s  ship    failed (3 attempts): out of stock",
+				"2024-05-01T12:01:04Z  +4s  charge  compensation failed: refund rejected",
+			},
+		},
+		{
+			name:    "show missing",
+			args:    []string{"show", "missing"},
+			wantErr: true,
+		},
+		{
+			name: "resume",
+			args: []string{"resume", "stuck"},
+			want: []string{"queued resume of execution stuck"},
+		},
+		{
+			name:    "resume finished",
+			args:    []string{"resume", "done"},
+			wantErr: true,
+		},
+		{
+			name: "compensate",
+			args: []string{"compensate", "done"},
+			want: []string{"queued compensate of execution done"},
+		},
+		{
+			name: "dead letters",
+			args: []string{"deadletters"},
+			want: []string{"failed  order  charge  2024-05-01T12:01:04Z  1         refund rejected"},
+		},
+		{
+			name:    "discard unknown dead letter",
+			args:    []string{"discard", "stuck"},
+			wantErr: true,
+		},
+		{
+			name: "discard",
+			args: []string{"discard", "failed"},
+			want: []string{"discarded dead letter failed"},
+		},
+		{
+			name:    "unknown command",
+			args:    []string{"purge"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var out bytes.Buffer
+				err := run(ctx, append([]string{"-dir", dir}, tt.args...), &out, now)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("run() error = %v, wantErr %t", err, tt.wantErr)
+				}
+				for _, want := range tt.want {
+					if !strings.Contains(out.String(), want) {
+						t.Errorf("output missing %q:\n%s", want, out.String())
+					}
+				}
+				for _, notWant := range tt.notWant {
+					if strings.Contains(out.String(), notWant) {
+						t.Errorf("output contains %q:\n%s", notWant, out.String())
+					}
+				}
+			},
+		)
+	}
+
+	if deadLetters, _ := store.DeadLetters(ctx); len(deadLetters) != 0 {
+		t.Errorf("dead letters = %v, want none", deadLetters)
+	}
+
+	commands, err := store.Commands(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 || commands[0].Action != goTx.CommandResume || commands[1].Action != goTx.CommandCompensate {
+		t.Errorf("commands = %v, want resume and compensate", commands)
+	}
+}
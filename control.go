@@ -0,0 +1,136 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrExecutionSuspended = errors.New("saga execution suspended")
+	ErrCompensationForced = errors.New("compensation forced")
+	ErrUnknownStep        = errors.New("unknown step")
+)
+
+type pausedError struct {
+	reason string
+}
+
+func (e *pausedError) Error() string {
+	return "paused: " + e.reason
+}
+
+// SuspendReason returns the reason a suspended execution is waiting for an
+// operator.
+func (e *SagaExecution) SuspendReason() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.suspendReason
+}
+
+// Pause asks the execution to stop before its next step, or before its next
+// compensation if it is compensating. The execution then becomes
+// ExecutionSuspended with the given reason, Execute returns an error wrapping
+// ErrExecutionSuspended and the execution waits for Resume or
+// ForceCompensate. The step that is running when Pause is called completes
+// first, except that a compensation waiting for its next retry stops waiting;
+// nested sagas pause only between their own steps.
+func (e *SagaExecution) Pause(reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch {
+	case e.state == ExecutionSuspended:
+		return nil
+	case e.interrupted:
+		return errors.New("cannot pause interrupted saga execution")
+	case e.state == ExecutionPending, e.state == ExecutionRunning:
+		e.pauseRequested = true
+		e.pauseReason = reason
+		if e.cancelRetry != nil {
+			e.cancelRetry()
+		}
+		return nil
+	default:
+		return fmt.Errorf("cannot pause %s saga execution", e.state)
+	}
+}
+
+// Skip marks a step to be skipped. A step that has not run yet is not run, and
+// a step that completed is not compensated; either way its status becomes
+// StepSkipped when the execution reaches it. Skipping the compensation that
+// keeps failing lets the operator undo the step by hand and Resume the
+// compensation of the other steps.
+func (e *SagaExecution) Skip(step string) error {
+	i := -1
+	for j, s := range e.def.steps {
+		if s.Name == step {
+			i = j
+		}
+	}
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownStep, step)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == ExecutionCompensated {
+		return fmt.Errorf("cannot skip step of %s saga execution", e.state)
+	}
+	switch status := e.steps[i].Status; status {
+	case StepPending, StepSucceeded, StepCompensationFailed:
+	default:
+		return fmt.Errorf("cannot skip %s step %q", status, step)
+	}
+
+	if e.skip == nil {
+		e.skip = make(map[string]bool)
+	}
+	e.skip[step] = true
+	return nil
+}
+
+// ForceCompensate makes the execution compensate its completed steps, as if
+// its next step had failed with an error wrapping ErrCompensationForced.
+//
+// A running execution is only asked to compensate: ForceCompensate returns nil
+// and the execution starts compensating once its current step completes, with
+// Execute returning the result. A suspended execution is compensated right
+// away, and a succeeded or failed execution is compensated like with
+// Compensate.
+func (e *SagaExecution) ForceCompensate(ctx context.Context, reason string) error {
+	e.mu.Lock()
+	switch {
+	case e.state == ExecutionRunning && !e.interrupted:
+		if !e.compensating {
+			e.forceRequested = true
+			e.forceReason = reason
+		}
+		e.mu.Unlock()
+		return nil
+	case e.state == ExecutionSuspended && e.compensating:
+		e.mu.Unlock()
+		return e.Resume(ctx)
+	case e.state == ExecutionSuspended:
+		e.state = ExecutionRunning
+		e.suspendReason = ""
+		e.mu.Unlock()
+		return e.withStoreErr(e.fail(ctx, fmt.Errorf("%w: %s", ErrCompensationForced, reason)))
+	default:
+		e.mu.Unlock()
+		return e.Compensate(ctx)
+	}
+}
+
+func (e *SagaExecution) suspend(ctx context.Context, reason string, cause error) error {
+	e.mu.Lock()
+	e.state = ExecutionSuspended
+	e.suspendReason = reason
+	e.err = cause
+	e.mu.Unlock()
+	e.persist(ctx)
+
+	return joinErrors(cause, fmt.Errorf("%w: %s", ErrExecutionSuspended, reason))
+}
@@ -0,0 +1,281 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSagaExecution_Control(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+	errRefund := errors.New("refund rejected")
+
+	// controlledStep runs control on the execution when its update runs.
+	controlledStep := func(log *stepLog, name string, exec **SagaExecution, control func(e *SagaExecution)) Step {
+		step := loggedStep(log, name, nil)
+		update := step.Update
+		step.Update = func(ctx context.Context) error {
+			control(*exec)
+			return update(ctx)
+		}
+		return step
+	}
+	failingCompensation := func(log *stepLog, name string, failures int) Step {
+		step := loggedStep(log, name, nil)
+		step.Compensate = func(context.Context) error {
+			if failures != 0 {
+				failures--
+				log.add("failed rollback " + name)
+				return errRefund
+			}
+			log.add("rollback " + name)
+			return nil
+		}
+		return step
+	}
+
+	tests := []struct {
+		name             string
+		steps            func(log *stepLog, exec **SagaExecution) []Step
+		suspendOnFailure bool
+		wantExecuteErr   []error
+		wantReason       string
+		operate          func(e *SagaExecution) error
+		wantState        ExecutionState
+		wantErr          error
+		wantLog          string
+		wantSkipped      []string
+	}{
+		{
+			name: "pause and resume",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					controlledStep(log, "a", exec, func(e *SagaExecution) { e.Pause("maintenance") }),
+					loggedStep(log, "b", nil),
+				}
+			},
+			wantExecuteErr: []error{ErrExecutionSuspended},
+			wantReason:     "maintenance",
+			operate: func(e *SagaExecution) error {
+				return e.Resume(context.Background())
+			},
+			wantState: ExecutionSucceeded,
+			wantLog:   "[a b]",
+		},
+		{
+			name: "skip step",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					controlledStep(log, "a", exec, func(e *SagaExecution) { e.Pause("b is broken") }),
+					loggedStep(log, "b", nil),
+					loggedStep(log, "c", nil),
+				}
+			},
+			wantExecuteErr: []error{ErrExecutionSuspended},
+			wantReason:     "b is broken",
+			operate: func(e *SagaExecution) error {
+				if err := e.Skip("b"); err != nil {
+					return err
+				}
+				return e.Resume(context.Background())
+			},
+			wantState:   ExecutionSucceeded,
+			wantLog:     "[a c]",
+			wantSkipped: []string{"b"},
+		},
+		{
+			name: "force compensation of running execution",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					controlledStep(log, "a", exec, func(e *SagaExecution) { e.ForceCompensate(context.Background(), "order cancelled") }),
+					loggedStep(log, "b", nil),
+				}
+			},
+			wantExecuteErr: []error{ErrCompensationForced},
+			wantState:      ExecutionCompensated,
+			wantErr:        ErrCompensationForced,
+			wantLog:        "[a rollback a]",
+		},
+		{
+			name: "force compensation of suspended execution",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					controlledStep(log, "a", exec, func(e *SagaExecution) { e.Pause("maintenance") }),
+					loggedStep(log, "b", nil),
+				}
+			},
+			wantExecuteErr: []error{ErrExecutionSuspended},
+			wantReason:     "maintenance",
+			operate: func(e *SagaExecution) error {
+				return e.ForceCompensate(context.Background(), "order cancelled")
+			},
+			wantState: ExecutionCompensated,
+			wantErr:   ErrCompensationForced,
+			wantLog:   "[a rollback a]",
+		},
+		{
+			name: "suspend on compensation failure and resume",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					failingCompensation(log, "a", 1),
+					loggedStep(log, "b", errOutOfStock),
+				}
+			},
+			suspendOnFailure: true,
+			wantExecuteErr:   []error{errOutOfStock, ErrExecutionSuspended},
+			wantReason:       `compensation failed: step "a": refund rejected`,
+			operate: func(e *SagaExecution) error {
+				return e.Resume(context.Background())
+			},
+			wantState: ExecutionCompensated,
+			wantErr:   errOutOfStock,
+			wantLog:   "[a b failed rollback a rollback a]",
+		},
+		{
+			name: "skip failing compensation",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					loggedStep(log, "a", nil),
+					failingCompensation(log, "b", -1),
+					loggedStep(log, "c", errOutOfStock),
+				}
+			},
+			suspendOnFailure: true,
+			wantExecuteErr:   []error{errOutOfStock, ErrExecutionSuspended},
+			wantReason:       `compensation failed: step "b": refund rejected`,
+			operate: func(e *SagaExecution) error {
+				if err := e.Skip("b"); err != nil {
+					return err
+				}
+				return e.Resume(context.Background())
+			},
+			wantState:   ExecutionCompensated,
+			wantErr:     errOutOfStock,
+			wantLog:     "[a b c failed rollback b rollback a]",
+			wantSkipped: []string{"b"},
+		},
+		{
+			name: "compensation failure without suspension",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				return []Step{
+					failingCompensation(log, "a", 1),
+					loggedStep(log, "b", errOutOfStock),
+				}
+			},
+			wantExecuteErr: []error{errOutOfStock, errRefund},
+			wantState:      ExecutionFailed,
+			wantErr:        errRefund,
+			wantLog:        "[a b failed rollback a]",
+		},
+		{
+			name: "pause while compensating",
+			steps: func(log *stepLog, exec **SagaExecution) []Step {
+				b := loggedStep(log, "b", nil)
+				b.Compensate = func(context.Context) error {
+					log.add("rollback b")
+					return (*exec).Pause("check refunds")
+				}
+				return []Step{
+					loggedStep(log, "a", nil),
+					b,
+					loggedStep(log, "c", errOutOfStock),
+				}
+			},
+			wantExecuteErr: []error{errOutOfStock, ErrExecutionSuspended},
+			wantReason:     "check refunds",
+			operate: func(e *SagaExecution) error {
+				if got := e.Err(); !errors.Is(got, errOutOfStock) {
+					t.Errorf("Err() while suspended = %v, want %v", got, errOutOfStock)
+				}
+				return e.Resume(context.Background())
+			},
+			wantState: ExecutionCompensated,
+			wantErr:   errOutOfStock,
+			wantLog:   "[a b c rollback b rollback a]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				log := &stepLog{}
+				var exec *SagaExecution
+
+				builder := NewSagaBuilder("order")
+				for _, step := range tt.steps(log, &exec) {
+					builder.AppendStep(step)
+				}
+				if tt.suspendOnFailure {
+					builder.WithSuspendOnCompensationFailure()
+				}
+				def, err := builder.Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec = def.NewExecution()
+				err = exec.Execute(context.Background())
+				for _, want := range tt.wantExecuteErr {
+					if !errors.Is(err, want) {
+						t.Errorf("Execute() error = %v, want %v", err, want)
+					}
+				}
+				if got := exec.SuspendReason(); got != tt.wantReason {
+					t.Errorf("SuspendReason() = %q, want %q", got, tt.wantReason)
+				}
+				if tt.wantReason != "" && !strings.Contains(exec.Report().SuspendReason, tt.wantReason) {
+					t.Errorf("Report().SuspendReason = %q, want %q", exec.Report().SuspendReason, tt.wantReason)
+				}
+
+				if tt.operate != nil {
+					if got := exec.State(); got != ExecutionSuspended {
+						t.Fatalf("State() = %s, want suspended", got)
+					}
+					err := tt.operate(exec)
+					if (err != nil) != (tt.wantErr != nil) || !errors.Is(err, tt.wantErr) {
+						t.Errorf("operation error = %v, want %v", err, tt.wantErr)
+					}
+				}
+
+				if got := exec.State(); got != tt.wantState {
+					t.Errorf("State() = %s, want %s", got, tt.wantState)
+				}
+				if got := exec.Err(); !errors.Is(got, tt.wantErr) || (got == nil) != (tt.wantErr == nil) {
+					t.Errorf("Err() = %v, want %v", got, tt.wantErr)
+				}
+				if got := log.String(); got != tt.wantLog {
+					t.Errorf("log = %q, want %q", got, tt.wantLog)
+				}
+				if got := exec.Report().StepsWithStatus(StepSkipped); strings.Join(got, ",") != strings.Join(tt.wantSkipped, ",") {
+					t.Errorf("skipped steps = %v, want %v", got, tt.wantSkipped)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaExecution_ControlErrors(t *testing.T) {
+	def, err := NewSagaBuilder("order").
+		AppendStep(loggedStep(&stepLog{}, "a", nil)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, err := def.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exec.Pause("too late"); err == nil {
+		t.Error("Pause() of succeeded execution error = nil, want error")
+	}
+	if err := exec.Skip("missing"); !errors.Is(err, ErrUnknownStep) {
+		t.Errorf("Skip() error = %v, want %v", err, ErrUnknownStep)
+	}
+	if err := exec.ForceCompensate(context.Background(), "undo"); err != nil {
+		t.Fatalf("ForceCompensate() error = %v", err)
+	}
+	if err := exec.Skip("a"); err == nil {
+		t.Error("Skip() of compensated execution error = nil, want error")
+	}
+}
@@ -0,0 +1,201 @@
+package goTx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDeadLetterMismatch is returned when retrying a dead letter with a saga it
+// does not belong to.
+var ErrDeadLetterMismatch = errors.New("dead letter belongs to another saga")
+
+// DeadLetter is a saga whose compensation could not complete, parked for an
+// operator to retry or discard. Dead letters are keyed by the ID of the
+// execution, so a failed retry replaces the dead letter it came from.
+type DeadLetter struct {
+	ID          string
+	ExecutionID string
+	Saga        string
+	// SagaTx is set on the dead letters of a SagaTx, which only that SagaTx
+	// can retry.
+	SagaTx bool
+	// Step is the step whose compensation failed.
+	Step string
+	// Cause is the error that made the saga compensate, if any.
+	Cause error
+	// Err is the error of the compensation.
+	Err       error
+	Attempts  int
+	CreatedAt time.Time
+	// Report is the report of the execution when its compensation failed. The
+	// steps of a SagaTx are named "step 1", "step 2" and so on.
+	Report *ExecutionReport
+}
+
+// DeadLetterStore keeps dead letters until they are retried or discarded.
+type DeadLetterStore interface {
+	AddDeadLetter(ctx context.Context, dl DeadLetter) error
+	DeadLetters(ctx context.Context) ([]DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+}
+
+type MemoryDeadLetterStore struct {
+	mu          sync.Mutex
+	deadLetters map[string]DeadLetter
+}
+
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{deadLetters: make(map[string]DeadLetter)}
+}
+
+func (s *MemoryDeadLetterStore) AddDeadLetter(_ context.Context, dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters[dl.ID] = dl
+	return nil
+}
+
+// DeadLetters returns the dead letters in the order they were created.
+func (s *MemoryDeadLetterStore) DeadLetters(_ context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadLetters := make([]DeadLetter, 0, len(s.deadLetters))
+	for _, dl := range s.deadLetters {
+		deadLetters = append(deadLetters, dl)
+	}
+	sortDeadLetters(deadLetters)
+	return deadLetters, nil
+}
+
+func (s *MemoryDeadLetterStore) DeleteDeadLetter(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deadLetters, id)
+	return nil
+}
+
+func sortDeadLetters(deadLetters []DeadLetter) {
+	sort.SliceStable(deadLetters, func(i, j int) bool {
+		return deadLetters[i].CreatedAt.Before(deadLetters[j].CreatedAt)
+	})
+}
+
+// RetryDeadLetter restores the execution of dl and compensates it again,
+// starting with the compensation that failed. The dead letter is removed from
+// store if the compensation completes; if it fails again and the saga has a
+// dead-letter store, the dead letter is replaced.
+func (d *SagaDefinition) RetryDeadLetter(ctx context.Context, store DeadLetterStore, dl DeadLetter) error {
+	if dl.SagaTx {
+		return fmt.Errorf("%w: %s is a dead letter of a SagaTx", ErrDeadLetterMismatch, dl.ID)
+	}
+	if dl.Report == nil {
+		return fmt.Errorf("dead letter %s has no saga execution report", dl.ID)
+	}
+
+	exec, err := d.RestoreExecution(dl.Report)
+	if err != nil {
+		return err
+	}
+	if err := exec.Compensate(ctx); err != nil {
+		return err
+	}
+	return store.DeleteDeadLetter(ctx, dl.ID)
+}
+
+// deadLetter parks the execution, whose compensation failed with err, in the
+// dead-letter store of the saga.
+func (e *SagaExecution) deadLetter(ctx context.Context, cause, err error) {
+	if e.def.deadLetters == nil || e.parent != nil {
+		return
+	}
+
+	e.mu.Lock()
+	step := e.steps[e.completedCount-1]
+	e.mu.Unlock()
+
+	dl := DeadLetter{
+		ID:          e.id,
+		ExecutionID: e.id,
+		Saga:        e.def.name,
+		Step:        step.Name,
+		Cause:       cause,
+		Err:         err,
+		Attempts:    step.CompensationAttempts,
+		CreatedAt:   time.Now(),
+		Report:      e.Report(),
+	}
+	if err := e.def.deadLetters.AddDeadLetter(context.WithoutCancel(ctx), dl); err != nil {
+		e.recordStoreErr(fmt.Errorf("saving dead letter: %w", err))
+	}
+}
+
+// clearDeadLetter removes the dead letter of an execution whose compensation
+// completed.
+func (e *SagaExecution) clearDeadLetter(ctx context.Context) {
+	if e.def.deadLetters == nil || e.parent != nil {
+		return
+	}
+
+	if err := e.def.deadLetters.DeleteDeadLetter(context.WithoutCancel(ctx), e.id); err != nil {
+		e.recordStoreErr(fmt.Errorf("deleting dead letter: %w", err))
+	}
+}
+
+type deadLetterJSON struct {
+	ID          string           `json:"id"`
+	ExecutionID string           `json:"execution_id,omitempty"`
+	Saga        string           `json:"saga"`
+	SagaTx      bool             `json:"saga_tx,omitempty"`
+	Step        string           `json:"step"`
+	Cause       string           `json:"cause,omitempty"`
+	Err         string           `json:"error"`
+	Attempts    int              `json:"attempts"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Report      *ExecutionReport `json:"report,omitempty"`
+}
+
+// MarshalJSON encodes errors as their message; UnmarshalJSON decodes them as
+// errors with the same message.
+func (dl DeadLetter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(deadLetterJSON{
+		ID:          dl.ID,
+		ExecutionID: dl.ExecutionID,
+		Saga:        dl.Saga,
+		SagaTx:      dl.SagaTx,
+		Step:        dl.Step,
+		Cause:       errorString(dl.Cause),
+		Err:         errorString(dl.Err),
+		Attempts:    dl.Attempts,
+		CreatedAt:   dl.CreatedAt,
+		Report:      dl.Report,
+	})
+}
+
+func (dl *DeadLetter) UnmarshalJSON(data []byte) error {
+	var v deadLetterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*dl = DeadLetter{
+		ID:          v.ID,
+		ExecutionID: v.ExecutionID,
+		Saga:        v.Saga,
+		SagaTx:      v.SagaTx,
+		Step:        v.Step,
+		Cause:       stringError(v.Cause),
+		Err:         stringError(v.Err),
+		Attempts:    v.Attempts,
+		CreatedAt:   v.CreatedAt,
+		Report:      v.Report,
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSagaDefinition_RetryDeadLetter(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+
+	tests := []struct {
+		name          string
+		store         func(t *testing.T) DeadLetterStore
+		failures      int
+		wantRetryErr  bool
+		wantAttempts  int
+		wantState     ExecutionState
+		wantLog       string
+		wantRemaining int
+	}{
+		{
+			name:          "memory store",
+			store:         func(*testing.T) DeadLetterStore { return NewMemoryDeadLetterStore() },
+			failures:      1,
+			wantAttempts:  1,
+			wantState:     ExecutionCompensated,
+			wantLog:       "[a b c failed rollback b rollback b rollback a]",
+			wantRemaining: 0,
+		},
+		{
+			name: "file store",
+			store: func(t *testing.T) DeadLetterStore {
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			},
+			failures:      1,
+			wantAttempts:  1,
+			wantState:     ExecutionCompensated,
+			wantLog:       "[a b c failed rollback b rollback b rollback a]",
+			wantRemaining: 0,
+		},
+		{
+			name:          "retry fails again",
+			store:         func(*testing.T) DeadLetterStore { return NewMemoryDeadLetterStore() },
+			failures:      2,
+			wantRetryErr:  true,
+			wantAttempts:  2,
+			wantState:     ExecutionFailed,
+			wantLog:       "[a b c failed rollback b failed rollback b]",
+			wantRemaining: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				store := tt.store(t)
+				log := &stepLog{}
+				failures := tt.failures
+
+				b := loggedStep(log, "b", nil)
+				b.Compensate = func(context.Context) error {
+					if failures > 0 {
+						failures--
+						log.add("failed rollback b")
+						return errors.New("refund rejected")
+					}
+					log.add("rollback b")
+					return nil
+				}
+				def, err := NewSagaBuilder("order").
+					WithDeadLetterStore(store).
+					AppendStep(loggedStep(log, "a", nil)).
+					AppendStep(b).
+					AppendStep(loggedStep(log, "c", errOutOfStock)).
+					Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec := def.NewExecutionWithID("order-1")
+				if err := exec.Execute(ctx); !errors.Is(err, errOutOfStock) {
+					t.Fatalf("Execute() error = %v, want %v", err, errOutOfStock)
+				}
+
+				deadLetters, err := store.DeadLetters(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(deadLetters) != 1 {
+					t.Fatalf("DeadLetters() = %v, want one dead letter", deadLetters)
+				}
+				dl := deadLetters[0]
+				if dl.ID != "order-1" || dl.ExecutionID != "order-1" || dl.Saga != "order" || dl.Step != "b" {
+					t.Errorf("dead letter = %+v, want step b of order-1", dl)
+				}
+				if dl.Cause == nil || !strings.Contains(dl.Cause.Error(), "out of stock") {
+					t.Errorf("dead letter cause = %v, want out of stock", dl.Cause)
+				}
+				if dl.Err == nil || !strings.Contains(dl.Err.Error(), "refund rejected") {
+					t.Errorf("dead letter error = %v, want refund rejected", dl.Err)
+				}
+				if dl.Report == nil || dl.Report.State != ExecutionFailed || dl.CreatedAt.IsZero() {
+					t.Errorf("dead letter report = %+v, want failed execution", dl.Report)
+				}
+
+				if err := def.RetryDeadLetter(ctx, store, dl); (err != nil) != tt.wantRetryErr {
+					t.Fatalf("RetryDeadLetter() error = %v, wantErr %t", err, tt.wantRetryErr)
+				}
+
+				deadLetters, err = store.DeadLetters(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(deadLetters) != tt.wantRemaining {
+					t.Fatalf("DeadLetters() after retry = %v, want %d", deadLetters, tt.wantRemaining)
+				}
+				if tt.wantRemaining > 0 {
+					if got := deadLetters[0].Attempts; got != tt.wantAttempts {
+						t.Errorf("dead letter attempts = %d, want %d", got, tt.wantAttempts)
+					}
+					if got := deadLetters[0].Report.State; got != tt.wantState {
+						t.Errorf("dead letter state = %s, want %s", got, tt.wantState)
+					}
+				}
+
+				if got := log.String(); got != tt.wantLog {
+					t.Errorf("log = %q, want %q", got, tt.wantLog)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaDefinition_RetryDeadLetter_Nested(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDeadLetterStore()
+	log := &stepLog{}
+	failures := 2
+
+	c1 := loggedStep(log, "c1", nil)
+	c1.Compensate = func(context.Context) error {
+		if failures > 0 {
+			failures--
+			log.add("failed rollback c1")
+			return errors.New("refund rejected")
+		}
+		log.add("rollback c1")
+		return nil
+	}
+	child, err := NewSagaBuilder("shipping").
+		AppendStep(c1).
+		AppendStep(loggedStep(log, "c2", errors.New("no carrier"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, err := NewSagaBuilder("order").
+		WithDeadLetterStore(store).
+		AppendStep(loggedStep(log, "p1", nil)).
+		AppendSaga("ship", child).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := def.NewExecutionWithID("order-1")
+	if err := exec.Execute(ctx); err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+	if exec.State() != ExecutionFailed {
+		t.Errorf("state = %s, want %s", exec.State(), ExecutionFailed)
+	}
+	deadLetters, err := store.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters) != 1 || deadLetters[0].Step != "ship" {
+		t.Fatalf("DeadLetters() = %+v, want one dead letter for step ship", deadLetters)
+	}
+
+	if err := def.RetryDeadLetter(ctx, store, deadLetters[0]); err != nil {
+		t.Fatalf("RetryDeadLetter() error = %v", err)
+	}
+	if deadLetters, _ := store.DeadLetters(ctx); len(deadLetters) != 0 {
+		t.Errorf("DeadLetters() after retry = %+v, want none", deadLetters)
+	}
+	want := "[p1 c1 c2 failed rollback c1 failed rollback c1 rollback c1 rollback p1]"
+	if got := log.String(); got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
+
+func TestTx_DeadLetter(t *testing.T) {
+	store := NewMemoryDeadLetterStore()
+	log := &stepLog{}
+	failures := 1
+
+	tx := NewSagaTx(false)
+	tx.SetName("order")
+	tx.SetDeadLetterStore(store)
+	tx.Append(
+		func() error { log.add("1"); return nil },
+		func() error { log.add("rollback 1"); return nil },
+	)
+	tx.Append(
+		func() error { log.add("2"); return nil },
+		func() error {
+			if failures > 0 {
+				failures--
+				return errors.New("refund rejected")
+			}
+			log.add("rollback 2")
+			return nil
+		},
+	)
+	tx.Append(
+		func() error { return errors.New("out of stock") },
+		func() error { log.add("rollback 3"); return nil },
+	)
+
+	err := tx.ExecuteAll()
+	if err == nil || !strings.Contains(err.Error(), "out of stock") || !strings.Contains(err.Error(), "refund rejected") {
+		t.Fatalf("ExecuteAll() error = %v, want step and compensation errors", err)
+	}
+
+	deadLetters, _ := store.DeadLetters(context.Background())
+	if len(deadLetters) != 1 {
+		t.Fatalf("DeadLetters() = %+v, want one", deadLetters)
+	}
+	dl := deadLetters[0]
+	if dl.ID != tx.ID() || dl.Saga != "order" || !dl.SagaTx || dl.Step != "step 2" || dl.Attempts != 1 {
+		t.Errorf("dead letter = %+v, want step 2 of SagaTx order %s", dl, tx.ID())
+	}
+	if dl.Report == nil {
+		t.Fatal("dead letter has no report")
+	}
+	var statuses []StepStatus
+	for _, step := range dl.Report.Steps {
+		statuses = append(statuses, step.Status)
+	}
+	if want := []StepStatus{StepSucceeded, StepCompensationFailed, StepCompensated}; !slices.Equal(statuses, want) {
+		t.Errorf("report statuses = %v, want %v", statuses, want)
+	}
+
+	def, err := NewSagaBuilder("order").AppendStep(loggedStep(log, "a", nil)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := def.RetryDeadLetter(context.Background(), store, dl); !errors.Is(err, ErrDeadLetterMismatch) {
+		t.Errorf("SagaDefinition.RetryDeadLetter() error = %v, want %v", err, ErrDeadLetterMismatch)
+	}
+	if err := NewSagaTx(false).RetryDeadLetter(context.Background(), store, dl); !errors.Is(err, ErrDeadLetterMismatch) {
+		t.Errorf("RetryDeadLetter() of another SagaTx error = %v, want %v", err, ErrDeadLetterMismatch)
+	}
+
+	if err := tx.RetryDeadLetter(context.Background(), store, dl); err != nil {
+		t.Fatalf("RetryDeadLetter() error = %v", err)
+	}
+	if got, want := log.String(), "[1 2 rollback 3 rollback 2 rollback 1]"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+	if deadLetters, _ := store.DeadLetters(context.Background()); len(deadLetters) != 0 {
+		t.Errorf("DeadLetters() after retry = %+v, want none", deadLetters)
+	}
+}
@@ -0,0 +1,312 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrEmptySagaName     = errors.New("saga name must not be empty")
+	ErrEmptyStepName     = errors.New("step name must not be empty")
+	ErrDuplicateStepName = errors.New("duplicate step name")
+	ErrMissingUpdate     = errors.New("step has no update function")
+)
+
+type StepFunc func(ctx context.Context) error
+
+type Step struct {
+	Name       string
+	Update     StepFunc
+	Compensate StepFunc
+
+	// Retry, if set, overrides the retry options of the saga for this step.
+	Retry *RetryOptions
+	// CompensateRetry, if set, overrides the compensation retry options of the
+	// saga for the compensation of this step.
+	CompensateRetry *RetryOptions
+	// Timeout, if set, bounds every attempt of the update.
+	Timeout time.Duration
+	// RateLimiter, if set, overrides the rate limiter of the saga for the
+	// update and compensation of this step.
+	RateLimiter *RateLimiter
+
+	child *SagaDefinition
+}
+
+// SagaDefinition is the immutable description of a saga. It is built once with a
+// SagaBuilder and can spawn any number of independent executions, which may run
+// concurrently.
+type SagaDefinition struct {
+	name  string
+	steps []Step
+
+	retries      bool
+	retryOptions RetryOptions
+
+	compensationRetries      bool
+	compensationRetryOptions RetryOptions
+
+	idempotencyStore IdempotencyStore
+	store            ExecutionStore
+
+	suspendOnCompensationFailure bool
+	deadLetters                  DeadLetterStore
+
+	rateLimiter *RateLimiter
+}
+
+type SagaBuilder struct {
+	def SagaDefinition
+}
+
+func NewSagaBuilder(name string) *SagaBuilder {
+	return &SagaBuilder{def: SagaDefinition{name: name}}
+}
+
+func (b *SagaBuilder) Append(name string, update UpdateFunc, compensate CompensateFunc) *SagaBuilder {
+	step := Step{Name: name}
+	if update != nil {
+		step.Update = func(context.Context) error { return update() }
+	}
+	if compensate != nil {
+		step.Compensate = func(context.Context) error { return compensate() }
+	}
+	return b.AppendStep(step)
+}
+
+func (b *SagaBuilder) AppendStep(step Step) *SagaBuilder {
+	b.def.steps = append(b.def.steps, step)
+	return b
+}
+
+// AppendSaga embeds child as a single step. Every execution of the parent runs
+// its own execution of child; if a later parent step fails the completed child
+// execution is compensated as a whole.
+func (b *SagaBuilder) AppendSaga(name string, child *SagaDefinition) *SagaBuilder {
+	return b.AppendStep(Step{Name: name, child: child})
+}
+
+func (b *SagaBuilder) WithRetries(options RetryOptions) *SagaBuilder {
+	b.def.retries = true
+	b.def.retryOptions = options
+	return b
+}
+
+// WithCompensationRetries makes executions retry failed compensations with
+// options. Unlike the update of a step, a compensation usually has to succeed
+// eventually, so options often use UnlimitedRetries with a capped backoff; a
+// compensation waiting for its next attempt can still be interrupted with
+// SagaExecution.Pause.
+func (b *SagaBuilder) WithCompensationRetries(options RetryOptions) *SagaBuilder {
+	b.def.compensationRetries = true
+	b.def.compensationRetryOptions = options
+	return b
+}
+
+// WithRateLimiter makes every attempt of the updates and compensations of the
+// saga wait for a token of limiter. The same limiter can be given to several
+// sagas to share its rate.
+func (b *SagaBuilder) WithRateLimiter(limiter *RateLimiter) *SagaBuilder {
+	b.def.rateLimiter = limiter
+	return b
+}
+
+// WithIdempotencyStore makes executions record the outcome of their steps in
+// store. Executions created with the same ID, for instance to recover an
+// interrupted execution, replay the recorded outcomes instead of invoking the
+// steps again.
+func (b *SagaBuilder) WithIdempotencyStore(store IdempotencyStore) *SagaBuilder {
+	b.def.idempotencyStore = store
+	return b
+}
+
+// WithStore makes executions save their report to store whenever a step starts
+// or completes, so that interrupted executions can be inspected and restored
+// with RestoreExecution. Executions of nested sagas are saved as part of the
+// report of their parent.
+func (b *SagaBuilder) WithStore(store ExecutionStore) *SagaBuilder {
+	b.def.store = store
+	return b
+}
+
+// WithSuspendOnCompensationFailure makes executions whose compensation fails
+// wait for an operator in ExecutionSuspended instead of ending in
+// ExecutionFailed. The operator can fix the cause and Resume the execution,
+// or Skip the compensation that keeps failing.
+func (b *SagaBuilder) WithSuspendOnCompensationFailure() *SagaBuilder {
+	b.def.suspendOnCompensationFailure = true
+	return b
+}
+
+// WithDeadLetterStore makes executions whose compensation fails park a
+// DeadLetter in store, with the report of the execution, so that it can be
+// retried with RetryDeadLetter or discarded. The dead letter of an execution is
+// removed once its compensation completes.
+func (b *SagaBuilder) WithDeadLetterStore(store DeadLetterStore) *SagaBuilder {
+	b.def.deadLetters = store
+	return b
+}
+
+func (b *SagaBuilder) Build() (*SagaDefinition, error) {
+	if b.def.name == "" {
+		return nil, ErrEmptySagaName
+	}
+
+	seen := make(map[string]bool, len(b.def.steps))
+	for _, step := range b.def.steps {
+		if step.Name == "" {
+			return nil, ErrEmptyStepName
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateStepName, step.Name)
+		}
+		seen[step.Name] = true
+
+		if step.Update == nil && step.child == nil {
+			return nil, fmt.Errorf("%w: %q", ErrMissingUpdate, step.Name)
+		}
+	}
+
+	def := b.def
+	def.steps = append([]Step(nil), b.def.steps...)
+	for i, step := range def.steps {
+		def.steps[i] = copyStep(step)
+	}
+	def.retryOptions.UnrecoverableErrors = append([]error(nil), b.def.retryOptions.UnrecoverableErrors...)
+	def.compensationRetryOptions.UnrecoverableErrors = append([]error(nil), b.def.compensationRetryOptions.UnrecoverableErrors...)
+	return &def, nil
+}
+
+// copyStep returns a copy of step that does not share its retry options.
+func copyStep(step Step) Step {
+	step.Retry = copyRetryOptions(step.Retry)
+	step.CompensateRetry = copyRetryOptions(step.CompensateRetry)
+	return step
+}
+
+func copyRetryOptions(options *RetryOptions) *RetryOptions {
+	if options == nil {
+		return nil
+	}
+	c := *options
+	c.UnrecoverableErrors = append([]error(nil), options.UnrecoverableErrors...)
+	return &c
+}
+
+func (d *SagaDefinition) Name() string {
+	return d.name
+}
+
+// Steps returns a copy of the steps of the saga. Changing their retry options
+// does not change the saga.
+func (d *SagaDefinition) Steps() []Step {
+	steps := make([]Step, len(d.steps))
+	for i, step := range d.steps {
+		steps[i] = copyStep(step)
+	}
+	return steps
+}
+
+// WrapSteps returns a copy of the definition, with the same options, whose
+// steps are replaced by fn(step). It descends into nested sagas; a step added
+// with AppendSaga is passed to fn with a nil Update and must be returned with
+// its Update still nil. It is meant for middleware such as logging or the fault
+// injection of package gotxtest.
+func (d *SagaDefinition) WrapSteps(fn func(step Step) Step) *SagaDefinition {
+	def := *d
+	def.steps = make([]Step, len(d.steps))
+	for i, step := range d.steps {
+		child := step.child
+		step = fn(copyStep(step))
+		if child != nil {
+			step.Update = nil
+			step.child = child.WrapSteps(fn)
+		}
+		def.steps[i] = step
+	}
+	return &def
+}
+
+func (d *SagaDefinition) NewExecution() *SagaExecution {
+	return d.NewExecutionWithID(newID())
+}
+
+func (d *SagaDefinition) NewExecutionWithID(id string) *SagaExecution {
+	steps := make([]StepReport, len(d.steps))
+	for i, step := range d.steps {
+		steps[i].Name = step.Name
+	}
+
+	return &SagaExecution{
+		id:       id,
+		def:      d,
+		state:    ExecutionPending,
+		children: make(map[int]*SagaExecution),
+		steps:    steps,
+	}
+}
+
+// RestoreExecution recreates an execution of the saga from a report, typically
+// loaded from an ExecutionStore. An execution whose report was saved while it
+// was running is considered interrupted and can be continued with Resume or
+// undone with Compensate.
+func (d *SagaDefinition) RestoreExecution(report *ExecutionReport) (*SagaExecution, error) {
+	if report.Saga != d.name {
+		return nil, fmt.Errorf("cannot restore execution of saga %q as %q", report.Saga, d.name)
+	}
+	if len(report.Steps) != len(d.steps) {
+		return nil, fmt.Errorf("cannot restore execution of saga %q: report has %d steps, saga has %d", d.name, len(report.Steps), len(d.steps))
+	}
+
+	e := d.NewExecutionWithID(report.ID)
+	e.state = report.State
+	e.err = report.Err
+	e.startedAt = report.StartedAt
+	e.finishedAt = report.FinishedAt
+	e.suspendReason = report.SuspendReason
+	e.interrupted = report.State == ExecutionRunning
+
+	for i, step := range report.Steps {
+		if step.Name != d.steps[i].Name {
+			return nil, fmt.Errorf("cannot restore execution of saga %q: step %d is %q, not %q", d.name, i, step.Name, d.steps[i].Name)
+		}
+		e.steps[i] = step
+		e.steps[i].Child = nil
+
+		if d.steps[i].child != nil && step.Child != nil {
+			child, err := d.steps[i].child.RestoreExecution(step.Child)
+			if err != nil {
+				return nil, err
+			}
+			child.parent = e
+			e.children[i] = child
+		}
+
+		// Steps still to be compensated are the ones that completed, and nested
+		// sagas that were interrupted half way.
+		switch step.Status {
+		case StepCompensating, StepCompensated, StepCompensationFailed:
+			e.compensating = true
+		case StepSkipped:
+			// Only steps that ran can have their compensation skipped.
+			e.compensating = e.compensating || !step.StartedAt.IsZero()
+		}
+		switch step.Status {
+		case StepSucceeded, StepCompensating, StepCompensationFailed, StepSkipped:
+			e.completedCount = i + 1
+		case StepRunning:
+			if e.children[i] != nil {
+				e.completedCount = i + 1
+			}
+		}
+	}
+	return e, nil
+}
+
+// Execute is a shorthand for creating a new execution and running it.
+func (d *SagaDefinition) Execute(ctx context.Context) (*SagaExecution, error) {
+	exec := d.NewExecution()
+	return exec, exec.Execute(ctx)
+}
@@ -0,0 +1,568 @@
+package goTx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrExecutionStarted = errors.New("saga execution already started")
+
+type ExecutionState int
+
+const (
+	ExecutionPending ExecutionState = iota
+	ExecutionRunning
+	ExecutionSucceeded
+	ExecutionCompensated
+	ExecutionFailed
+	ExecutionSuspended
+)
+
+func (s ExecutionState) String() string {
+	switch s {
+	case ExecutionPending:
+		return "pending"
+	case ExecutionRunning:
+		return "running"
+	case ExecutionSucceeded:
+		return "succeeded"
+	case ExecutionCompensated:
+		return "compensated"
+	case ExecutionFailed:
+		return "failed"
+	case ExecutionSuspended:
+		return "suspended"
+	default:
+		return fmt.Sprintf("ExecutionState(%d)", int(s))
+	}
+}
+
+// SagaExecution is a single run of a SagaDefinition. Unlike SagaTx only the
+// steps that completed are compensated when a step fails; a failed step is
+// expected to leave no effect behind. If a compensation fails the remaining
+// compensations are not attempted and the execution ends up ExecutionFailed.
+type SagaExecution struct {
+	id  string
+	def *SagaDefinition
+
+	mu             sync.Mutex
+	state          ExecutionState
+	err            error
+	completedCount int
+	children       map[int]*SagaExecution
+	steps          []StepReport
+	startedAt      time.Time
+	finishedAt     time.Time
+
+	// parent is set for the executions of sagas embedded with AppendSaga, which
+	// are persisted as part of the report of their parent.
+	parent *SagaExecution
+	// interrupted is set for executions restored from a report that was saved
+	// while they were running, for instance before the process crashed.
+	interrupted bool
+	storeErr    error
+
+	// compensating is set once the execution started compensating its steps.
+	compensating   bool
+	suspendReason  string
+	pauseRequested bool
+	pauseReason    string
+	forceRequested bool
+	forceReason    string
+	skip           map[string]bool
+	// cancelRetry interrupts the wait for the next attempt of the compensation
+	// that is being retried.
+	cancelRetry context.CancelFunc
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (e *SagaExecution) ID() string {
+	return e.id
+}
+
+func (e *SagaExecution) Definition() *SagaDefinition {
+	return e.def
+}
+
+func (e *SagaExecution) State() ExecutionState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.state
+}
+
+// Err returns the error the execution ended with, or nil if it succeeded or has
+// not finished yet. For an execution suspended while compensating it returns
+// the error that caused the compensation.
+func (e *SagaExecution) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.err
+}
+
+func (e *SagaExecution) Execute(ctx context.Context) error {
+	e.mu.Lock()
+	if e.state != ExecutionPending {
+		e.mu.Unlock()
+		return ErrExecutionStarted
+	}
+	e.state = ExecutionRunning
+	e.startedAt = time.Now()
+	e.mu.Unlock()
+
+	e.persist(ctx)
+	return e.withStoreErr(e.run(ctx))
+}
+
+// Resume continues an interrupted execution, restored with
+// SagaDefinition.RestoreExecution, from its first step that did not complete.
+// The step that was running when the execution was interrupted runs again, so
+// steps should be idempotent or the saga should have an IdempotencyStore.
+//
+// Resume also continues a suspended execution, from the step it was paused
+// before or, if it was suspended while compensating, with the compensation it
+// was blocked on.
+func (e *SagaExecution) Resume(ctx context.Context) error {
+	e.mu.Lock()
+	if e.state == ExecutionSuspended {
+		cause := e.err
+		compensating := e.compensating
+		e.state = ExecutionRunning
+		e.err = nil
+		e.suspendReason = ""
+		e.mu.Unlock()
+
+		if compensating {
+			return e.withStoreErr(e.compensateAndFinish(context.WithoutCancel(ctx), cause))
+		}
+		e.persist(ctx)
+		return e.withStoreErr(e.run(ctx))
+	}
+
+	if !e.interrupted {
+		e.mu.Unlock()
+		return fmt.Errorf("cannot resume %s saga execution", e.state)
+	}
+	if e.compensating {
+		e.mu.Unlock()
+		return errors.New("cannot resume saga execution that was being compensated")
+	}
+	e.interrupted = false
+	e.completedCount = 0
+	for e.completedCount < len(e.steps) {
+		status := e.steps[e.completedCount].Status
+		if status != StepSucceeded && status != StepSkipped {
+			break
+		}
+		e.completedCount++
+	}
+	e.mu.Unlock()
+
+	return e.withStoreErr(e.run(ctx))
+}
+
+func (e *SagaExecution) run(ctx context.Context) error {
+	e.mu.Lock()
+	from := e.completedCount
+	e.mu.Unlock()
+
+	for i := from; i < len(e.def.steps); i++ {
+		step := e.def.steps[i]
+
+		e.mu.Lock()
+		force, forceReason := e.forceRequested, e.forceReason
+		pause, pauseReason := e.pauseRequested, e.pauseReason
+		skip := e.skip[step.Name]
+		e.forceRequested, e.pauseRequested = false, false
+		e.mu.Unlock()
+
+		switch {
+		case force:
+			return e.fail(ctx, fmt.Errorf("%w: %s", ErrCompensationForced, forceReason))
+		case pause:
+			return e.suspend(ctx, pauseReason, nil)
+		case skip:
+			e.updateStep(i, func(r *StepReport) { r.Status = StepSkipped })
+		default:
+			err := ctx.Err()
+			if err == nil {
+				err = e.runStep(ctx, i, step)
+			}
+			if err != nil {
+				// A nested saga whose compensation failed still has to be
+				// compensated by the parent.
+				e.mu.Lock()
+				if e.steps[i].Status == StepCompensationFailed {
+					e.completedCount = i + 1
+				}
+				e.mu.Unlock()
+				return e.fail(ctx, fmt.Errorf("step %q: %w", step.Name, err))
+			}
+		}
+
+		e.mu.Lock()
+		e.completedCount = i + 1
+		e.mu.Unlock()
+		e.persist(ctx)
+	}
+
+	e.finish(ctx, ExecutionSucceeded, nil)
+	return nil
+}
+
+func (e *SagaExecution) runStep(ctx context.Context, i int, step Step) error {
+	start := time.Now()
+	e.updateStep(i, func(r *StepReport) {
+		r.Status = StepRunning
+		r.StartedAt = start
+	})
+	e.persist(ctx)
+
+	attempts := 0
+	update := func(ctx context.Context) error {
+		attempts++
+		e.updateStep(i, func(r *StepReport) { r.Attempts = attempts })
+		if err := e.waitForRateLimit(ctx, i, step, ScopeUpdate); err != nil {
+			return err
+		}
+		if step.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+			defer cancel()
+		}
+		return safeStep(step.Update)(ctx)
+	}
+
+	var err error
+	var childFailed bool
+	if step.child != nil {
+		e.mu.Lock()
+		child, ok := e.children[i]
+		e.mu.Unlock()
+
+		if ok && child.interrupted {
+			err = child.Resume(ctx)
+		} else {
+			child = step.child.NewExecutionWithID(e.id + "/" + step.Name)
+			child.parent = e
+			e.mu.Lock()
+			e.children[i] = child
+			e.mu.Unlock()
+
+			e.updateStep(i, func(r *StepReport) { r.Attempts = 1 })
+			err = child.Execute(ctx)
+		}
+		childFailed = err != nil && child.State() == ExecutionFailed
+	} else {
+		key := stepIdempotencyKey(e.id, step.Name, ScopeUpdate)
+		var replayed bool
+		replayed, err = runIdempotent(ctx, e.def.idempotencyStore, key, true, func(ctx context.Context) error {
+			if step.Retry != nil {
+				return RetryContext(ctx, update, *step.Retry)
+			}
+			if e.def.retries {
+				return RetryContext(ctx, update, e.def.retryOptions)
+			}
+			return update(ctx)
+		})
+		e.updateStep(i, func(r *StepReport) { r.Replayed = replayed })
+	}
+
+	e.updateStep(i, func(r *StepReport) {
+		r.Duration = time.Since(start)
+		r.Err = err
+		switch {
+		case childFailed:
+			r.Status = StepCompensationFailed
+			r.CompensationErr = err
+		case err != nil:
+			r.Status = StepFailed
+		default:
+			r.Status = StepSucceeded
+		}
+	})
+	return err
+}
+
+// waitForRateLimit waits for a token of the rate limiter of step, if it has
+// one, before an attempt of its update or compensation, and adds the time it
+// waited to the report of the step.
+func (e *SagaExecution) waitForRateLimit(ctx context.Context, i int, step Step, scope IdempotencyScope) error {
+	limiter := step.RateLimiter
+	if limiter == nil {
+		limiter = e.def.rateLimiter
+	}
+	if limiter == nil {
+		return nil
+	}
+
+	wait, err := limiter.Wait(ctx)
+	e.updateStep(i, func(r *StepReport) {
+		if scope == ScopeCompensate {
+			r.CompensationRateLimitWait += wait
+		} else {
+			r.RateLimitWait += wait
+		}
+	})
+	return err
+}
+
+func (e *SagaExecution) fail(ctx context.Context, err error) error {
+	return e.compensateAndFinish(context.WithoutCancel(ctx), err)
+}
+
+// compensateAndFinish compensates the completed steps of an execution that
+// failed with cause, or that is compensated on request if cause is nil.
+func (e *SagaExecution) compensateAndFinish(ctx context.Context, cause error) error {
+	e.mu.Lock()
+	e.compensating = true
+	e.mu.Unlock()
+
+	err := e.compensate(ctx)
+	var paused *pausedError
+	switch {
+	case errors.As(err, &paused):
+		return e.suspend(ctx, paused.reason, cause)
+	case err != nil && e.def.suspendOnCompensationFailure:
+		return e.suspend(ctx, fmt.Sprintf("compensation failed: %v", err), cause)
+	case err != nil:
+		cerr := err
+		err = joinErrors(cause, fmt.Errorf("compensation failed: %w", err))
+		e.finish(ctx, ExecutionFailed, err)
+		e.deadLetter(ctx, cause, cerr)
+		return err
+	}
+
+	e.finish(ctx, ExecutionCompensated, cause)
+	e.clearDeadLetter(ctx)
+	return cause
+}
+
+// Compensate undoes a succeeded execution by compensating all its steps in
+// reverse order. It also resumes the compensation of an execution whose
+// compensation failed earlier, and compensates the steps an interrupted
+// execution completed. Compensating an execution that was already compensated
+// is a no-op.
+func (e *SagaExecution) Compensate(ctx context.Context) error {
+	e.mu.Lock()
+	state := e.state
+	interrupted := e.interrupted
+	e.interrupted = false
+	e.mu.Unlock()
+
+	switch {
+	case state == ExecutionCompensated:
+		return nil
+	case state == ExecutionSucceeded, state == ExecutionFailed, interrupted:
+	default:
+		return fmt.Errorf("cannot compensate %s saga execution", state)
+	}
+
+	return e.withStoreErr(e.compensateAndFinish(ctx, nil))
+}
+
+func (e *SagaExecution) compensate(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		i := e.completedCount - 1
+		e.mu.Unlock()
+		if i < 0 {
+			return nil
+		}
+		step := e.def.steps[i]
+
+		e.mu.Lock()
+		pause, pauseReason := e.pauseRequested, e.pauseReason
+		skip := e.skip[step.Name] || e.steps[i].Status == StepSkipped
+		e.pauseRequested = false
+		e.mu.Unlock()
+
+		switch {
+		case pause:
+			return &pausedError{reason: pauseReason}
+		case skip:
+			e.updateStep(i, func(r *StepReport) { r.Status = StepSkipped })
+		default:
+			if err := e.compensateStep(ctx, i, step); err != nil {
+				e.mu.Lock()
+				pause, pauseReason := e.pauseRequested, e.pauseReason
+				e.pauseRequested = false
+				e.mu.Unlock()
+				if pause {
+					return &pausedError{reason: pauseReason}
+				}
+				return fmt.Errorf("step %q: %w", step.Name, err)
+			}
+		}
+
+		e.mu.Lock()
+		e.completedCount = i
+		e.mu.Unlock()
+		e.persist(ctx)
+	}
+}
+
+func (e *SagaExecution) compensateStep(ctx context.Context, i int, step Step) error {
+	start := time.Now()
+	e.updateStep(i, func(r *StepReport) {
+		r.Status = StepCompensating
+		r.CompensationStartedAt = start
+	})
+	e.persist(ctx)
+
+	var err error
+	if step.child != nil {
+		e.mu.Lock()
+		child := e.children[i]
+		e.mu.Unlock()
+		e.updateStep(i, func(r *StepReport) { r.CompensationAttempts++ })
+		err = child.ForceCompensate(ctx, "parent saga compensated")
+	} else if step.Compensate != nil {
+		retry := step.CompensateRetry
+		if retry == nil && e.def.compensationRetries {
+			retry = &e.def.compensationRetryOptions
+		}
+		waitCtx, cancel := context.WithCancel(ctx)
+		e.mu.Lock()
+		e.cancelRetry = cancel
+		e.mu.Unlock()
+
+		key := stepIdempotencyKey(e.id, step.Name, ScopeCompensate)
+		var replayed bool
+		replayed, err = runIdempotent(ctx, e.def.idempotencyStore, key, false, func(ctx context.Context) error {
+			compensate := func(context.Context) error {
+				e.updateStep(i, func(r *StepReport) { r.CompensationAttempts++ })
+				if err := e.waitForRateLimit(ctx, i, step, ScopeCompensate); err != nil {
+					return err
+				}
+				return safeStep(step.Compensate)(ctx)
+			}
+			if retry == nil {
+				return compensate(ctx)
+			}
+			return RetryContext(waitCtx, compensate, *retry)
+		})
+		e.updateStep(i, func(r *StepReport) { r.CompensationReplayed = replayed })
+
+		e.mu.Lock()
+		e.cancelRetry = nil
+		e.mu.Unlock()
+		cancel()
+	}
+
+	e.updateStep(i, func(r *StepReport) {
+		r.CompensationDuration += time.Since(start)
+		r.CompensationErr = err
+		if err != nil {
+			r.Status = StepCompensationFailed
+		} else {
+			r.Status = StepCompensated
+		}
+	})
+	return err
+}
+
+func (e *SagaExecution) finish(ctx context.Context, state ExecutionState, err error) {
+	e.mu.Lock()
+	e.state = state
+	e.err = err
+	e.finishedAt = time.Now()
+	e.mu.Unlock()
+
+	e.persist(ctx)
+}
+
+// persist saves the report of the execution to the ExecutionStore of the saga.
+// A failure to save does not change the course of the execution; it is
+// returned together with the outcome of the execution.
+func (e *SagaExecution) persist(ctx context.Context) {
+	if e.parent != nil {
+		e.parent.persist(ctx)
+		return
+	}
+	if e.def.store == nil {
+		return
+	}
+
+	if err := e.def.store.Save(context.WithoutCancel(ctx), e.Report()); err != nil {
+		e.recordStoreErr(&saveError{fmt.Errorf("saving saga execution: %w", err)})
+	}
+}
+
+// saveError is an error saving the report of an execution to the store of its
+// saga, which leaves the stored report out of date.
+type saveError struct {
+	err error
+}
+
+func (e *saveError) Error() string {
+	return e.err.Error()
+}
+
+func (e *saveError) Unwrap() error {
+	return e.err
+}
+
+func (e *SagaExecution) recordStoreErr(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.storeErr == nil {
+		e.storeErr = err
+	}
+}
+
+func (e *SagaExecution) withStoreErr(err error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	storeErr := e.storeErr
+	e.storeErr = nil
+	if storeErr == nil {
+		return err
+	}
+	return joinErrors(err, storeErr)
+}
+
+func (e *SagaExecution) updateStep(i int, fn func(r *StepReport)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fn(&e.steps[i])
+}
+
+// Report returns a snapshot of the execution and the status of each of its
+// steps.
+func (e *SagaExecution) Report() *ExecutionReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	report := &ExecutionReport{
+		ID:         e.id,
+		Saga:       e.def.name,
+		State:      e.state,
+		Err:        e.err,
+		StartedAt:  e.startedAt,
+		FinishedAt: e.finishedAt,
+		Steps:      append([]StepReport(nil), e.steps...),
+
+		SuspendReason: e.suspendReason,
+	}
+	for i, child := range e.children {
+		report.Steps[i].Child = child.Report()
+	}
+	return report
+}
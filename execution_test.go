@@ -0,0 +1,440 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stepLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *stepLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+}
+
+func (l *stepLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return fmt.Sprint(l.entries)
+}
+
+func loggedStep(log *stepLog, name string, err error) Step {
+	return Step{
+		Name: name,
+		Update: func(context.Context) error {
+			log.add(name)
+			return err
+		},
+		Compensate: func(context.Context) error {
+			log.add("rollback " + name)
+			return nil
+		},
+	}
+}
+
+func TestSagaExecution_Execute(t *testing.T) {
+	errStep := errors.New("step error")
+	tests := []struct {
+		name      string
+		steps     func(log *stepLog) []Step
+		wantErr   bool
+		wantState ExecutionState
+		wantLog   []string
+	}{
+		{
+			name: "happypath",
+			steps: func(log *stepLog) []Step {
+				return []Step{loggedStep(log, "a", nil), loggedStep(log, "b", nil), loggedStep(log, "c", nil)}
+			},
+			wantState: ExecutionSucceeded,
+			wantLog:   []string{"a", "b", "c"},
+		},
+		{
+			name: "error-mid",
+			steps: func(log *stepLog) []Step {
+				return []Step{loggedStep(log, "a", nil), loggedStep(log, "b", errStep), loggedStep(log, "c", nil)}
+			},
+			wantErr:   true,
+			wantState: ExecutionCompensated,
+			wantLog:   []string{"a", "b", "rollback a"},
+		},
+		{
+			name: "compensation-fails",
+			steps: func(log *stepLog) []Step {
+				b := loggedStep(log, "b", nil)
+				b.Compensate = func(context.Context) error { return errStep }
+				return []Step{loggedStep(log, "a", nil), b, loggedStep(log, "c", errStep)}
+			},
+			wantErr:   true,
+			wantState: ExecutionFailed,
+			wantLog:   []string{"a", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				log := &stepLog{}
+				builder := NewSagaBuilder("test")
+				for _, step := range tt.steps(log) {
+					builder.AppendStep(step)
+				}
+				def, err := builder.Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec, err := def.Execute(context.Background())
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr && !errors.Is(err, errStep) {
+					t.Errorf("Execute() error = %v, want %v", err, errStep)
+				}
+				if exec.State() != tt.wantState {
+					t.Errorf("State() = %v, want %v", exec.State(), tt.wantState)
+				}
+				if log.String() != fmt.Sprint(tt.wantLog) {
+					t.Errorf("log = %v, want %v", log, tt.wantLog)
+				}
+				if err := exec.Execute(context.Background()); !errors.Is(err, ErrExecutionStarted) {
+					t.Errorf("second Execute() error = %v, want %v", err, ErrExecutionStarted)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaExecution_Concurrent(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		balance int
+	)
+	def, err := NewSagaBuilder("deposit").
+		Append("deposit", func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			balance += 10
+			return nil
+		}, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			balance -= 10
+			return nil
+		}).
+		WithRetries(RetryOptions{MaxRetries: 3, Backoff: &ExponentialBackoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 2}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	ids := make(chan string, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exec, err := def.Execute(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			ids <- exec.ID()
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("duplicate execution ID %q", id)
+		}
+		seen[id] = true
+	}
+	if balance != 100 {
+		t.Errorf("balance = %d, want 100", balance)
+	}
+}
+
+func TestSagaExecution_Nested(t *testing.T) {
+	log := &stepLog{}
+	child, err := NewSagaBuilder("child").
+		AppendStep(loggedStep(log, "c1", nil)).
+		AppendStep(loggedStep(log, "c2", nil)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := NewSagaBuilder("parent").
+		AppendStep(loggedStep(log, "p1", nil)).
+		AppendSaga("child", child).
+		AppendStep(loggedStep(log, "p2", errors.New("parent error"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec, err := parent.Execute(context.Background())
+	if err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+	want := []string{"p1", "c1", "c2", "p2", "rollback c2", "rollback c1", "rollback p1"}
+	if log.String() != fmt.Sprint(want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+	if exec.State() != ExecutionCompensated {
+		t.Errorf("State() = %v, want %v", exec.State(), ExecutionCompensated)
+	}
+}
+
+func TestSagaDefinition_StepsAreCopies(t *testing.T) {
+	def, err := NewSagaBuilder("saga").
+		AppendStep(Step{
+			Name:            "a",
+			Update:          func(context.Context) error { return nil },
+			Retry:           &RetryOptions{MaxRetries: 3},
+			CompensateRetry: &RetryOptions{MaxRetries: 3},
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*SagaDefinition)
+	}{
+		{
+			name: "Steps",
+			modify: func(def *SagaDefinition) {
+				step := def.Steps()[0]
+				step.Retry.MaxRetries = 0
+				step.CompensateRetry.MaxRetries = 0
+			},
+		},
+		{
+			name: "WrapSteps",
+			modify: func(def *SagaDefinition) {
+				def.WrapSteps(func(step Step) Step {
+					step.Retry.MaxRetries = 0
+					step.CompensateRetry.MaxRetries = 0
+					return step
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				tt.modify(def)
+				if step := def.steps[0]; step.Retry.MaxRetries != 3 || step.CompensateRetry.MaxRetries != 3 {
+					t.Errorf("retries = %d and %d, want the definition unchanged", step.Retry.MaxRetries, step.CompensateRetry.MaxRetries)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaBuilder_Build(t *testing.T) {
+	noop := func() error { return nil }
+	tests := []struct {
+		name    string
+		builder *SagaBuilder
+		wantErr error
+	}{
+		{
+			name:    "empty-saga-name",
+			builder: NewSagaBuilder("").Append("a", noop, noop),
+			wantErr: ErrEmptySagaName,
+		},
+		{
+			name:    "empty-step-name",
+			builder: NewSagaBuilder("saga").Append("", noop, noop),
+			wantErr: ErrEmptyStepName,
+		},
+		{
+			name:    "duplicate-step-name",
+			builder: NewSagaBuilder("saga").Append("a", noop, noop).Append("a", noop, noop),
+			wantErr: ErrDuplicateStepName,
+		},
+		{
+			name:    "missing-update",
+			builder: NewSagaBuilder("saga").Append("a", nil, noop),
+			wantErr: ErrMissingUpdate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if _, err := tt.builder.Build(); !errors.Is(err, tt.wantErr) {
+					t.Errorf("Build() error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaExecution_StepTimeout(t *testing.T) {
+	def, err := NewSagaBuilder("timeout").
+		AppendStep(Step{
+			Name:    "slow",
+			Timeout: 10 * time.Millisecond,
+			Update: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := def.Execute(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSagaExecution_CompensationRetries(t *testing.T) {
+	errRefund := errors.New("refund rejected")
+	quick := RetryOptions{MaxRetries: 3}
+
+	tests := []struct {
+		name         string
+		sagaRetries  *RetryOptions
+		stepRetries  *RetryOptions
+		failures     int
+		wantState    ExecutionState
+		wantAttempts int
+	}{
+		{
+			name:         "no retries",
+			failures:     1,
+			wantState:    ExecutionFailed,
+			wantAttempts: 1,
+		},
+		{
+			name:         "saga retries",
+			sagaRetries:  &quick,
+			failures:     2,
+			wantState:    ExecutionCompensated,
+			wantAttempts: 3,
+		},
+		{
+			name:         "step retries override saga retries",
+			sagaRetries:  &RetryOptions{MaxRetries: 1},
+			stepRetries:  &quick,
+			failures:     2,
+			wantState:    ExecutionCompensated,
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			sagaRetries:  &quick,
+			failures:     5,
+			wantState:    ExecutionFailed,
+			wantAttempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				failures := tt.failures
+				updates := 0
+				a := Step{
+					Name: "a",
+					Update: func(context.Context) error {
+						updates++
+						return nil
+					},
+					Compensate: func(context.Context) error {
+						if failures > 0 {
+							failures--
+							return errRefund
+						}
+						return nil
+					},
+					CompensateRetry: tt.stepRetries,
+				}
+				b := Step{Name: "b", Update: func(context.Context) error { return errors.New("out of stock") }}
+
+				builder := NewSagaBuilder("order").
+					WithRetries(RetryOptions{MaxRetries: 5}).
+					AppendStep(a).
+					AppendStep(b)
+				if tt.sagaRetries != nil {
+					builder.WithCompensationRetries(*tt.sagaRetries)
+				}
+				def, err := builder.Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec, _ := def.Execute(context.Background())
+				if got := exec.State(); got != tt.wantState {
+					t.Errorf("State() = %s, want %s", got, tt.wantState)
+				}
+				step, _ := exec.Report().Step("a")
+				if step.CompensationAttempts != tt.wantAttempts {
+					t.Errorf("CompensationAttempts = %d, want %d", step.CompensationAttempts, tt.wantAttempts)
+				}
+				if updates != 1 {
+					t.Errorf("updates = %d, want 1", updates)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaExecution_PauseUnlimitedCompensationRetries(t *testing.T) {
+	attempts := make(chan int, 100)
+	n := 0
+	def, err := NewSagaBuilder("order").
+		WithCompensationRetries(RetryOptions{
+			MaxRetries: UnlimitedRetries,
+			Backoff:    &ConstantBackoff{Interval: time.Hour},
+		}).
+		AppendStep(Step{
+			Name:   "a",
+			Update: func(context.Context) error { return nil },
+			Compensate: func(context.Context) error {
+				n++
+				attempts <- n
+				return errors.New("refund rejected")
+			},
+		}).
+		AppendStep(Step{Name: "b", Update: func(context.Context) error { return errors.New("out of stock") }}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := def.NewExecution()
+	done := make(chan error)
+	go func() { done <- exec.Execute(context.Background()) }()
+
+	<-attempts
+	if err := exec.Pause("refunds are down"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrExecutionSuspended) {
+			t.Errorf("Execute() error = %v, want %v", err, ErrExecutionSuspended)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Pause() did not interrupt the compensation retries")
+	}
+	if got := exec.SuspendReason(); got != "refunds are down" {
+		t.Errorf("SuspendReason() = %q, want %q", got, "refunds are down")
+	}
+}
@@ -1,10 +1,10 @@
 package goTx
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 type Operator interface {
@@ -13,6 +13,11 @@ type Operator interface {
 	ExecuteAll() error
 }
 
+// Chain executes a sequence of operations. Each operation is a fallback sequence:
+// its primary function is tried first and, if it fails, each alternate in order
+// until one succeeds. If every alternate of an operation fails, the chain fails
+// and the cleanup actions of the operations that already succeeded run in
+// reverse order.
 type Chain struct {
 	RetryOptions
 
@@ -20,19 +25,84 @@ type Chain struct {
 	async   bool
 	retries bool
 
-	lock           sync.Mutex
-	completedCount int
-	completedErr   error
-	errs           error
+	lock    sync.Mutex
+	results []OperationResult
+
+	scheduler   Scheduler
+	rateLimiter *RateLimiter
 }
 
 type ChainOperation struct {
-	tryFunc     func() error
-	secondaryOp *ChainOperation
+	name       string
+	alternates []StepFunc
+	cleanup    StepFunc
+	hedgeDelay time.Duration
+}
+
+type OperationResult struct {
+	Name string
+	// Alternate is the index of the function that succeeded: 0 for the primary
+	// function, 1 for the first alternate and so on. It is -1 if the operation
+	// did not succeed.
+	Alternate int
+	// Errors holds the errors of the functions that were tried and failed, in
+	// the order they were tried.
+	Errors []error
+	// FailedAlternates holds the index of the function that failed with each
+	// error of Errors. An error of the context that stopped the operation is
+	// last in Errors and has no index.
+	FailedAlternates []int
+
+	CleanedUp  bool
+	CleanupErr error
+	// Hedged is set if more than one function of a hedged operation was
+	// running at the same time.
+	Hedged bool
 }
 
+func (r OperationResult) Succeeded() bool {
+	return r.Alternate >= 0
+}
+
+// NewOperation creates an operation that tries try and falls back to
+// secondaryOp, and in turn to the fallbacks of secondaryOp, if it fails. The
+// operation takes the name, cleanup and hedging delay of secondaryOp.
 func NewOperation(try UpdateFunc, secondaryOp *ChainOperation) *ChainOperation {
-	return &ChainOperation{tryFunc: try, secondaryOp: secondaryOp}
+	op := &ChainOperation{alternates: []StepFunc{func(context.Context) error { return try() }}}
+	if secondaryOp != nil {
+		op.name = secondaryOp.name
+		op.alternates = append(op.alternates, secondaryOp.alternates...)
+		op.cleanup = secondaryOp.cleanup
+		op.hedgeDelay = secondaryOp.hedgeDelay
+	}
+	return op
+}
+
+func NewFallbackOperation(name string, primary StepFunc, alternates ...StepFunc) *ChainOperation {
+	return &ChainOperation{
+		name:       name,
+		alternates: append([]StepFunc{primary}, alternates...),
+	}
+}
+
+func (o *ChainOperation) Name() string {
+	return o.name
+}
+
+// WithCleanup sets the action that undoes the operation if a later operation of
+// the chain fails.
+func (o *ChainOperation) WithCleanup(cleanup StepFunc) *ChainOperation {
+	o.cleanup = cleanup
+	return o
+}
+
+// WithHedging makes the operation launch its next alternate concurrently when
+// the functions already running have not returned within delay, or as soon as
+// all of them failed. The first function to succeed wins and the context of the
+// others is cancelled.
+func (o *ChainOperation) WithHedging(delay time.Duration) *ChainOperation {
+	o.hedgeDelay = delay
+	return o
 }
 
 func NewChain(async bool) *Chain {
@@ -52,113 +122,252 @@ func NewChain(async bool) *Chain {
 	}
 }
 
+// SetRetries enables or disables retrying failed alternates with
+// RetryOptions.
+func (t *Chain) SetRetries(enabled bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.retries = enabled
+}
+
+// SetRateLimiter makes every attempt of the alternates and cleanups of the
+// chain, including retries, wait for a token of limiter.
+func (t *Chain) SetRateLimiter(limiter *RateLimiter) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.rateLimiter = limiter
+}
+
+// SetScheduler makes an async chain run its operations with scheduler, and
+// makes retries wait on its clock unless their options have a clock of their
+// own. Hedging needs real timers, so with a scheduler the alternates of a hedged
+// operation are tried one after another.
+func (t *Chain) SetScheduler(scheduler Scheduler) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.scheduler = scheduler
+}
+
 func (t *Chain) Append(operation *ChainOperation) {
 	t.ops = append(t.ops, operation)
 }
 
+// Do executes a single operation and its fallbacks outside of the chain's
+// sequence.
 func (t *Chain) Do(operation *ChainOperation) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	return t.execute(operation)
+	result := t.execute(context.Background(), operation)
+	t.results = []OperationResult{result}
+	if !result.Succeeded() {
+		return operationError(result)
+	}
+
+	return nil
 }
 
-func (t *Chain) execute(operation *ChainOperation) error {
-	t.completedErr = nil
+func (t *Chain) ExecuteAll() error {
+	_, err := t.Execute(context.Background())
+	return err
+}
 
-	var err error
-	if t.retries {
-		err = Retry(operation.tryFunc, t.RetryOptions)
-	} else {
-		err = operation.tryFunc()
+// Execute runs all operations of the chain and returns the result of each one.
+// In async mode the operations run concurrently and Execute waits for all of
+// them before cleaning up.
+func (t *Chain) Execute(ctx context.Context) ([]OperationResult, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.results = make([]OperationResult, len(t.ops))
+	for i, op := range t.ops {
+		t.results[i] = OperationResult{Name: op.name, Alternate: -1}
 	}
 
-	if err != nil {
-		if operation.secondaryOp != nil {
-			return t.execute(operation.secondaryOp)
-		} else {
-			t.handleCompletion(err)
-			return t.completedErr
+	var errs []error
+	if t.async {
+		tasks := make([]func(), len(t.ops))
+		for i, op := range t.ops {
+			tasks[i] = func() { t.results[i] = t.execute(ctx, op) }
 		}
-	}
+		schedulerOrDefault(t.scheduler).Go(tasks...)()
 
-	t.handleCompletion(nil)
+		for _, result := range t.results {
+			if !result.Succeeded() {
+				errs = append(errs, operationError(result))
+			}
+		}
+	} else {
+		for i, op := range t.ops {
+			t.results[i] = t.execute(ctx, op)
+			if !t.results[i].Succeeded() {
+				errs = append(errs, operationError(t.results[i]))
+				break
+			}
+		}
+	}
 
-	if t.completedErr != nil {
-		return t.completedErr
+	if len(errs) > 0 {
+		if cerr := t.cleanup(context.WithoutCancel(ctx)); cerr != nil {
+			errs = append(errs, fmt.Errorf("cleanup failed: %w", cerr))
+		}
+		return t.copyResults(), joinErrors(errs...)
 	}
 
-	return nil
+	return t.copyResults(), nil
 }
 
-func (t *Chain) ExecuteAll() error {
+// Results returns the result of each operation of the last execution. It waits
+// for an execution that is running to finish.
+func (t *Chain) Results() []OperationResult {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	return t.executeAll()
-}
-
-func (t *Chain) executeAll() error {
-	t.completedCount = 0
-	t.completedErr = nil
-
-	for _, o := range t.ops {
-		op := o
-		if t.async {
-			go func() {
-				err := t.execute(op)
-				if err != nil {
-					t.handleCompletion(err)
-				}
-			}()
-		} else {
-			err := t.execute(op)
-			if err != nil {
-				t.handleCompletion(err)
-			}
-		}
+	return t.copyResults()
+}
+
+// copyResults returns a copy of the results. t.lock must be held.
+func (t *Chain) copyResults() []OperationResult {
+	return append([]OperationResult(nil), t.results...)
+}
+
+func (t *Chain) execute(ctx context.Context, operation *ChainOperation) OperationResult {
+	if operation.hedgeDelay > 0 && len(operation.alternates) > 1 && t.scheduler == nil {
+		return t.executeHedged(ctx, operation)
 	}
 
-	if t.completedErr != nil {
-		t.doSecondary()
-		return t.completedErr
+	result := OperationResult{Name: operation.name, Alternate: -1}
+	for i, fn := range operation.alternates {
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, err)
+			return result
+		}
+
+		if err := t.try(ctx, fn); err != nil {
+			result.Errors = append(result.Errors, err)
+			result.FailedAlternates = append(result.FailedAlternates, i)
+			continue
+		}
+		result.Alternate = i
+		return result
 	}
 
-	return nil
+	return result
 }
 
-func (t *Chain) handleCompletion(err error) {
-	if err != nil {
-		t.completedErr = err
-		t.errs = errors.Wrap(t.completedErr, err.Error())
+func (t *Chain) executeHedged(ctx context.Context, operation *ChainOperation) OperationResult {
+	type outcome struct {
+		alternate int
+		err       error
+	}
+
+	result := OperationResult{Name: operation.name, Alternate: -1}
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make(chan outcome, len(operation.alternates))
+	errs := make([]error, len(operation.alternates))
+	launched, running := 0, 0
+	launch := func() {
+		i := launched
+		launched++
+		running++
+		if running > 1 {
+			result.Hedged = true
+		}
+		go func() {
+			outcomes <- outcome{alternate: i, err: t.try(hedgeCtx, operation.alternates[i])}
+		}()
 	}
 
-	t.completedCount++
+	timer := time.NewTimer(operation.hedgeDelay)
+	defer timer.Stop()
+	resetTimer := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(operation.hedgeDelay)
+	}
 
-	if t.completedErr != nil {
-		t.doSecondary()
+	launch()
+	for running > 0 {
+		select {
+		case <-ctx.Done():
+			result.Errors, result.FailedAlternates = collectErrors(errs)
+			result.Errors = append(result.Errors, ctx.Err())
+			return result
+		case <-timer.C:
+			if launched < len(operation.alternates) {
+				launch()
+				timer.Reset(operation.hedgeDelay)
+			}
+		case o := <-outcomes:
+			running--
+			if o.err == nil {
+				result.Alternate = o.alternate
+				result.Errors, result.FailedAlternates = collectErrors(errs)
+				return result
+			}
+			errs[o.alternate] = o.err
+			if running == 0 && launched < len(operation.alternates) {
+				launch()
+				resetTimer()
+			}
+		}
 	}
+
+	result.Errors, result.FailedAlternates = collectErrors(errs)
+	return result
 }
 
-func (t *Chain) handleChildOpCompletion(err error) {
-	if err != nil {
-		t.completedErr = err
-		t.errs = errors.Wrap(t.completedErr, err.Error())
+func (t *Chain) try(ctx context.Context, fn StepFunc) error {
+	fn = limited(t.rateLimiter, safeStep(fn))
+	if t.retries {
+		return RetryContext(ctx, fn, withClock(t.RetryOptions, t.scheduler))
 	}
+	return fn(ctx)
+}
 
-	t.completedCount++
+func (t *Chain) cleanup(ctx context.Context) error {
+	var errs []error
+	for i := len(t.results) - 1; i >= 0; i-- {
+		result := &t.results[i]
+		op := t.ops[i]
+		if !result.Succeeded() || op.cleanup == nil {
+			continue
+		}
 
-	if t.completedErr != nil {
-		t.doSecondary()
+		result.CleanupErr = limited(t.rateLimiter, safeStep(op.cleanup))(ctx)
+		if result.CleanupErr != nil {
+			errs = append(errs, fmt.Errorf("operation %q: %w", op.name, result.CleanupErr))
+			continue
+		}
+		result.CleanedUp = true
 	}
+	return joinErrors(errs...)
 }
 
-func (t *Chain) doSecondary() {
-	for i := t.completedCount - 1; i >= 0; i-- {
-		rollbackOp := t.ops[i]
-		err := rollbackOp.tryFunc()
+// collectErrors returns the non-nil errors of errs, indexed by alternate, and
+// their alternates.
+func collectErrors(errs []error) (collected []error, alternates []int) {
+	for i, err := range errs {
 		if err != nil {
-			panic(err)
+			collected = append(collected, err)
+			alternates = append(alternates, i)
 		}
 	}
+	return collected, alternates
+}
+
+func operationError(result OperationResult) error {
+	if len(result.Errors) == 0 {
+		return fmt.Errorf("operation %q failed", result.Name)
+	}
+	return fmt.Errorf("operation %q: all %d alternatives failed: %w", result.Name, len(result.Errors), joinErrors(result.Errors...))
 }
@@ -1,8 +1,11 @@
 package goTx
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 var f = 1
@@ -34,6 +37,72 @@ func TestOps(t *testing.T) {
 	}
 }
 
+func TestNewOperation(t *testing.T) {
+	errOp := errors.New("operation error")
+	var cleanedUp bool
+	secondary := NewFallbackOperation("read", func(context.Context) error { return nil }).
+		WithCleanup(func(context.Context) error {
+			cleanedUp = true
+			return nil
+		}).
+		WithHedging(10 * time.Millisecond)
+	op := NewOperation(func() error { return errOp }, secondary)
+
+	if op.Name() != "read" {
+		t.Errorf("Name() = %q, want %q", op.Name(), "read")
+	}
+	if op.hedgeDelay != 10*time.Millisecond {
+		t.Errorf("hedgeDelay = %v, want %v", op.hedgeDelay, 10*time.Millisecond)
+	}
+
+	ch := NewChain(false)
+	ch.Append(op)
+	ch.Append(NewFallbackOperation("write", func(context.Context) error { return errOp }))
+	results, err := ch.Execute(context.Background())
+	if !errors.Is(err, errOp) {
+		t.Fatalf("Execute() error = %v, want %v", err, errOp)
+	}
+	if results[0].Name != "read" || results[0].Alternate != 1 {
+		t.Errorf("result = %+v, want read succeeding with alternate 1", results[0])
+	}
+	if !cleanedUp || !results[0].CleanedUp {
+		t.Error("cleanup of the secondary operation did not run")
+	}
+}
+
+func TestChain_Results(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ch := NewChain(true)
+	ch.Append(NewFallbackOperation("read", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	done := make(chan []OperationResult)
+	go func() {
+		results, _ := ch.Execute(context.Background())
+		done <- results
+	}()
+	<-started
+
+	got := make(chan []OperationResult)
+	go func() { got <- ch.Results() }()
+	select {
+	case results := <-got:
+		t.Fatalf("Results() = %+v while the chain runs, want it to wait", results)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	want := <-done
+	results := <-got
+	if len(results) != 1 || results[0].Name != want[0].Name || results[0].Alternate != want[0].Alternate {
+		t.Errorf("Results() = %+v, want %+v", results, want)
+	}
+}
+
 func doSomething() error {
 	f += 1
 	fmt.Println(f)
@@ -51,3 +120,196 @@ func undoEverything() error {
 	fmt.Println(f)
 	return nil
 }
+
+func TestChain_Execute(t1 *testing.T) {
+	errOp := errors.New("operation error")
+	tests := []struct {
+		name          string
+		async         bool
+		ops           func(log *stepLog) []*ChainOperation
+		wantErr       bool
+		wantAlternate []int
+		wantLog       []string
+	}{
+		{
+			name: "primary-succeeds",
+			ops: func(log *stepLog) []*ChainOperation {
+				return []*ChainOperation{
+					fallbackOp(log, "a", nil, nil),
+					fallbackOp(log, "b", nil),
+				}
+			},
+			wantAlternate: []int{0, 0},
+			wantLog:       []string{"a0", "b0"},
+		},
+		{
+			name: "alternate-succeeds",
+			ops: func(log *stepLog) []*ChainOperation {
+				return []*ChainOperation{
+					fallbackOp(log, "a", errOp, errOp, nil),
+					fallbackOp(log, "b", errOp, nil),
+				}
+			},
+			wantAlternate: []int{2, 1},
+			wantLog:       []string{"a0", "a1", "a2", "b0", "b1"},
+		},
+		{
+			name: "all-alternates-fail",
+			ops: func(log *stepLog) []*ChainOperation {
+				return []*ChainOperation{
+					fallbackOp(log, "a", nil),
+					fallbackOp(log, "b", errOp, nil),
+					fallbackOp(log, "c", errOp, errOp),
+					fallbackOp(log, "d", nil),
+				}
+			},
+			wantErr:       true,
+			wantAlternate: []int{0, 1, -1, -1},
+			wantLog:       []string{"a0", "b0", "b1", "c0", "c1", "cleanup b", "cleanup a"},
+		},
+		{
+			name:  "async-fails",
+			async: true,
+			ops: func(log *stepLog) []*ChainOperation {
+				return []*ChainOperation{
+					fallbackOp(log, "a", nil),
+					fallbackOp(log, "b", errOp),
+				}
+			},
+			wantErr:       true,
+			wantAlternate: []int{0, -1},
+		},
+	}
+	for _, tt := range tests {
+		t1.Run(
+			tt.name, func(t1 *testing.T) {
+				log := &stepLog{}
+				ch := NewChain(tt.async)
+				for _, op := range tt.ops(log) {
+					ch.Append(op)
+				}
+
+				results, err := ch.Execute(context.Background())
+				if (err != nil) != tt.wantErr {
+					t1.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr && !errors.Is(err, errOp) {
+					t1.Errorf("Execute() error = %v, want %v", err, errOp)
+				}
+
+				var alternates []int
+				for _, result := range results {
+					alternates = append(alternates, result.Alternate)
+				}
+				if fmt.Sprint(alternates) != fmt.Sprint(tt.wantAlternate) {
+					t1.Errorf("alternates = %v, want %v", alternates, tt.wantAlternate)
+				}
+				if tt.wantLog != nil && log.String() != fmt.Sprint(tt.wantLog) {
+					t1.Errorf("log = %v, want %v", log, tt.wantLog)
+				}
+				if tt.async && tt.wantErr && !results[0].CleanedUp {
+					t1.Errorf("results[0].CleanedUp = false, want true")
+				}
+			},
+		)
+	}
+}
+
+func fallbackOp(log *stepLog, name string, errs ...error) *ChainOperation {
+	fns := make([]StepFunc, len(errs))
+	for i, err := range errs {
+		i, err := i, err
+		fns[i] = func(context.Context) error {
+			log.add(fmt.Sprintf("%s%d", name, i))
+			return err
+		}
+	}
+	return NewFallbackOperation(name, fns[0], fns[1:]...).WithCleanup(func(context.Context) error {
+		log.add("cleanup " + name)
+		return nil
+	})
+}
+
+func TestChain_Hedging(t1 *testing.T) {
+	errOp := errors.New("operation error")
+	slow := func(cancelled chan<- struct{}) StepFunc {
+		return func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				cancelled <- struct{}{}
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+	}
+	fast := func(err error) StepFunc {
+		return func(context.Context) error { return err }
+	}
+
+	tests := []struct {
+		name          string
+		primary       func(cancelled chan<- struct{}) StepFunc
+		secondary     func(cancelled chan<- struct{}) StepFunc
+		wantAlternate int
+		wantHedged    bool
+		wantCancelled bool
+		wantErrs      int
+	}{
+		{
+			name:          "primary-fast",
+			primary:       func(chan<- struct{}) StepFunc { return fast(nil) },
+			secondary:     func(chan<- struct{}) StepFunc { return fast(nil) },
+			wantAlternate: 0,
+		},
+		{
+			name:          "primary-slow",
+			primary:       slow,
+			secondary:     func(chan<- struct{}) StepFunc { return fast(nil) },
+			wantAlternate: 1,
+			wantHedged:    true,
+			wantCancelled: true,
+		},
+		{
+			name:          "primary-fails",
+			primary:       func(chan<- struct{}) StepFunc { return fast(errOp) },
+			secondary:     func(chan<- struct{}) StepFunc { return fast(nil) },
+			wantAlternate: 1,
+			wantErrs:      1,
+		},
+		{
+			name:          "all-fail",
+			primary:       func(chan<- struct{}) StepFunc { return fast(errOp) },
+			secondary:     func(chan<- struct{}) StepFunc { return fast(errOp) },
+			wantAlternate: -1,
+			wantErrs:      2,
+		},
+	}
+	for _, tt := range tests {
+		t1.Run(
+			tt.name, func(t1 *testing.T) {
+				cancelled := make(chan struct{}, 2)
+				ch := NewChain(false)
+				ch.Append(NewFallbackOperation(tt.name, tt.primary(cancelled), tt.secondary(cancelled)).WithHedging(10 * time.Millisecond))
+
+				results, _ := ch.Execute(context.Background())
+				if results[0].Alternate != tt.wantAlternate {
+					t1.Errorf("Alternate = %d, want %d", results[0].Alternate, tt.wantAlternate)
+				}
+				if results[0].Hedged != tt.wantHedged {
+					t1.Errorf("Hedged = %v, want %v", results[0].Hedged, tt.wantHedged)
+				}
+				if len(results[0].Errors) != tt.wantErrs {
+					t1.Errorf("Errors = %v, want %d errors", results[0].Errors, tt.wantErrs)
+				}
+				if tt.wantCancelled {
+					select {
+					case <-cancelled:
+					case <-time.After(500 * time.Millisecond):
+						t1.Error("losing operation was not cancelled")
+					}
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,168 @@
+package goTx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a database/sql driver that records the statements and transaction
+// boundaries it sees. Queries are answered by the query func.
+type fakeDB struct {
+	mu     sync.Mutex
+	log    []string
+	txOpts []driver.TxOptions
+
+	execErr func(query string) error
+	query   func(query string, args []driver.Value) ([]string, [][]driver.Value, error)
+}
+
+func newFakeDB() (*sql.DB, *fakeDB) {
+	fake := &fakeDB{}
+	return sql.OpenDB(fake), fake
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) Driver() driver.Driver {
+	return f
+}
+
+func (f *fakeDB) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: f}, nil
+}
+
+func (f *fakeDB) record(entry string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.log = append(f.log, entry)
+}
+
+func (f *fakeDB) entries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.log...)
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	c.db.txOpts = append(c.db.txOpts, opts)
+	c.db.mu.Unlock()
+
+	c.db.record("begin")
+	return &fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (t *fakeTx) Commit() error {
+	t.db.record("commit")
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.db.record("rollback")
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.db.execErr != nil {
+		if err := s.db.execErr(s.query); err != nil {
+			s.db.record("failed " + s.query)
+			return nil, err
+		}
+	}
+
+	s.db.record(s.query + formatArgs(args))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query + formatArgs(args))
+	if s.db.query == nil {
+		return &fakeRows{}, nil
+	}
+
+	columns, rows, err := s.db.query(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func formatArgs(args []driver.Value) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			arg = string(b)
+		}
+		formatted[i] = fmt.Sprint(arg)
+	}
+	return " [" + strings.Join(formatted, " ") + "]"
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
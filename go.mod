@@ -1,5 +1,5 @@
 module github.com/interwubs/goTx
 
-go 1.18
+go 1.22
 
-require github.com/pkg/errors v0.9.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
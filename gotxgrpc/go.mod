@@ -0,0 +1,21 @@
+module github.com/interwubs/goTx/gotxgrpc
+
+go 1.25.0
+
+require (
+	github.com/interwubs/goTx v0.0.0-20261018214754-92802125d8d1
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.12
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Builds in this repository use the root module next to it. Dependents ignore
+// the replacement and get the version required above.
+replace github.com/interwubs/goTx => ../
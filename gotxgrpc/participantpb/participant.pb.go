@@ -0,0 +1,332 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: participantpb/participant.proto
+
+package participantpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StepState int32
+
+const (
+	StepState_STEP_STATE_UNSPECIFIED         StepState = 0
+	StepState_STEP_STATE_RUNNING             StepState = 1
+	StepState_STEP_STATE_SUCCEEDED           StepState = 2
+	StepState_STEP_STATE_FAILED              StepState = 3
+	StepState_STEP_STATE_COMPENSATED         StepState = 4
+	StepState_STEP_STATE_COMPENSATION_FAILED StepState = 5
+)
+
+// Enum value maps for StepState.
+var (
+	StepState_name = map[int32]string{
+		0: "STEP_STATE_UNSPECIFIED",
+		1: "STEP_STATE_RUNNING",
+		2: "STEP_STATE_SUCCEEDED",
+		3: "STEP_STATE_FAILED",
+		4: "STEP_STATE_COMPENSATED",
+		5: "STEP_STATE_COMPENSATION_FAILED",
+	}
+	StepState_value = map[string]int32{
+		"STEP_STATE_UNSPECIFIED":         0,
+		"STEP_STATE_RUNNING":             1,
+		"STEP_STATE_SUCCEEDED":           2,
+		"STEP_STATE_FAILED":              3,
+		"STEP_STATE_COMPENSATED":         4,
+		"STEP_STATE_COMPENSATION_FAILED": 5,
+	}
+)
+
+func (x StepState) Enum() *StepState {
+	p := new(StepState)
+	*p = x
+	return p
+}
+
+func (x StepState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StepState) Descriptor() protoreflect.EnumDescriptor {
+	return file_participantpb_participant_proto_enumTypes[0].Descriptor()
+}
+
+func (StepState) Type() protoreflect.EnumType {
+	return &file_participantpb_participant_proto_enumTypes[0]
+}
+
+func (x StepState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StepState.Descriptor instead.
+func (StepState) EnumDescriptor() ([]byte, []int) {
+	return file_participantpb_participant_proto_rawDescGZIP(), []int{0}
+}
+
+type StepRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Step           string                 `protobuf:"bytes,1,opt,name=step,proto3" json:"step,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	Input          []byte                 `protobuf:"bytes,3,opt,name=input,proto3" json:"input,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StepRequest) Reset() {
+	*x = StepRequest{}
+	mi := &file_participantpb_participant_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepRequest) ProtoMessage() {}
+
+func (x *StepRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_participantpb_participant_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepRequest.ProtoReflect.Descriptor instead.
+func (*StepRequest) Descriptor() ([]byte, []int) {
+	return file_participantpb_participant_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StepRequest) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *StepRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *StepRequest) GetInput() []byte {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+type StatusRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Step           string                 `protobuf:"bytes,1,opt,name=step,proto3" json:"step,omitempty"`
+	IdempotencyKey string                 `protobuf:"bytes,2,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_participantpb_participant_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_participantpb_participant_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_participantpb_participant_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatusRequest) GetStep() string {
+	if x != nil {
+		return x.Step
+	}
+	return ""
+}
+
+func (x *StatusRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type StepResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         StepState              `protobuf:"varint,1,opt,name=state,proto3,enum=gotx.participant.v1.StepState" json:"state,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StepResponse) Reset() {
+	*x = StepResponse{}
+	mi := &file_participantpb_participant_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StepResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepResponse) ProtoMessage() {}
+
+func (x *StepResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_participantpb_participant_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepResponse.ProtoReflect.Descriptor instead.
+func (*StepResponse) Descriptor() ([]byte, []int) {
+	return file_participantpb_participant_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StepResponse) GetState() StepState {
+	if x != nil {
+		return x.State
+	}
+	return StepState_STEP_STATE_UNSPECIFIED
+}
+
+func (x *StepResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_participantpb_participant_proto protoreflect.FileDescriptor
+
+const file_participantpb_participant_proto_rawDesc = "" +
+	"\n" +
+	"\x1fparticipantpb/participant.proto\x12\x13gotx.participant.v1\"`\n" +
+	"\vStepRequest\x12\x12\n" +
+	"\x04step\x18\x01 \x01(\tR\x04step\x12'\n" +
+	"\x0fidempotency_key\x18\x02 \x01(\tR\x0eidempotencyKey\x12\x14\n" +
+	"\x05input\x18\x03 \x01(\fR\x05input\"L\n" +
+	"\rStatusRequest\x12\x12\n" +
+	"\x04step\x18\x01 \x01(\tR\x04step\x12'\n" +
+	"\x0fidempotency_key\x18\x02 \x01(\tR\x0eidempotencyKey\"Z\n" +
+	"\fStepResponse\x124\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x1e.gotx.participant.v1.StepStateR\x05state\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error*\xb0\x01\n" +
+	"\tStepState\x12\x1a\n" +
+	"\x16STEP_STATE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12STEP_STATE_RUNNING\x10\x01\x12\x18\n" +
+	"\x14STEP_STATE_SUCCEEDED\x10\x02\x12\x15\n" +
+	"\x11STEP_STATE_FAILED\x10\x03\x12\x1a\n" +
+	"\x16STEP_STATE_COMPENSATED\x10\x04\x12\"\n" +
+	"\x1eSTEP_STATE_COMPENSATION_FAILED\x10\x052\x81\x02\n" +
+	"\vParticipant\x12N\n" +
+	"\aExecute\x12 .gotx.participant.v1.StepRequest\x1a!.gotx.participant.v1.StepResponse\x12Q\n" +
+	"\n" +
+	"Compensate\x12 .gotx.participant.v1.StepRequest\x1a!.gotx.participant.v1.StepResponse\x12O\n" +
+	"\x06Status\x12\".gotx.participant.v1.StatusRequest\x1a!.gotx.participant.v1.StepResponseB2Z0github.com/interwubs/goTx/gotxgrpc/participantpbb\x06proto3"
+
+var (
+	file_participantpb_participant_proto_rawDescOnce sync.Once
+	file_participantpb_participant_proto_rawDescData []byte
+)
+
+func file_participantpb_participant_proto_rawDescGZIP() []byte {
+	file_participantpb_participant_proto_rawDescOnce.Do(func() {
+		file_participantpb_participant_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_participantpb_participant_proto_rawDesc), len(file_participantpb_participant_proto_rawDesc)))
+	})
+	return file_participantpb_participant_proto_rawDescData
+}
+
+var file_participantpb_participant_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_participantpb_participant_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_participantpb_participant_proto_goTypes = []any{
+	(StepState)(0),        // 0: gotx.participant.v1.StepState
+	(*StepRequest)(nil),   // 1: gotx.participant.v1.StepRequest
+	(*StatusRequest)(nil), // 2: gotx.participant.v1.StatusRequest
+	(*StepResponse)(nil),  // 3: gotx.participant.v1.StepResponse
+}
+var file_participantpb_participant_proto_depIdxs = []int32{
+	0, // 0: gotx.participant.v1.StepResponse.state:type_name -> gotx.participant.v1.StepState
+	1, // 1: gotx.participant.v1.Participant.Execute:input_type -> gotx.participant.v1.StepRequest
+	1, // 2: gotx.participant.v1.Participant.Compensate:input_type -> gotx.participant.v1.StepRequest
+	2, // 3: gotx.participant.v1.Participant.Status:input_type -> gotx.participant.v1.StatusRequest
+	3, // 4: gotx.participant.v1.Participant.Execute:output_type -> gotx.participant.v1.StepResponse
+	3, // 5: gotx.participant.v1.Participant.Compensate:output_type -> gotx.participant.v1.StepResponse
+	3, // 6: gotx.participant.v1.Participant.Status:output_type -> gotx.participant.v1.StepResponse
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_participantpb_participant_proto_init() }
+func file_participantpb_participant_proto_init() {
+	if File_participantpb_participant_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_participantpb_participant_proto_rawDesc), len(file_participantpb_participant_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_participantpb_participant_proto_goTypes,
+		DependencyIndexes: file_participantpb_participant_proto_depIdxs,
+		EnumInfos:         file_participantpb_participant_proto_enumTypes,
+		MessageInfos:      file_participantpb_participant_proto_msgTypes,
+	}.Build()
+	File_participantpb_participant_proto = out.File
+	file_participantpb_participant_proto_goTypes = nil
+	file_participantpb_participant_proto_depIdxs = nil
+}
@@ -0,0 +1,207 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: participantpb/participant.proto
+
+package participantpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Participant_Execute_FullMethodName    = "/gotx.participant.v1.Participant/Execute"
+	Participant_Compensate_FullMethodName = "/gotx.participant.v1.Participant/Compensate"
+	Participant_Status_FullMethodName     = "/gotx.participant.v1.Participant/Status"
+)
+
+// ParticipantClient is the client API for Participant service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Participant is implemented by services that take part in a saga as remote
+// steps. Execute and Compensate must be idempotent for a given idempotency key.
+type ParticipantClient interface {
+	Execute(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*StepResponse, error)
+	Compensate(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*StepResponse, error)
+	// Status returns the outcome of the last Execute or Compensate call made with
+	// the idempotency key of the request.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StepResponse, error)
+}
+
+type participantClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewParticipantClient(cc grpc.ClientConnInterface) ParticipantClient {
+	return &participantClient{cc}
+}
+
+func (c *participantClient) Execute(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*StepResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StepResponse)
+	err := c.cc.Invoke(ctx, Participant_Execute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *participantClient) Compensate(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*StepResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StepResponse)
+	err := c.cc.Invoke(ctx, Participant_Compensate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *participantClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StepResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StepResponse)
+	err := c.cc.Invoke(ctx, Participant_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ParticipantServer is the server API for Participant service.
+// All implementations must embed UnimplementedParticipantServer
+// for forward compatibility.
+//
+// Participant is implemented by services that take part in a saga as remote
+// steps. Execute and Compensate must be idempotent for a given idempotency key.
+type ParticipantServer interface {
+	Execute(context.Context, *StepRequest) (*StepResponse, error)
+	Compensate(context.Context, *StepRequest) (*StepResponse, error)
+	// Status returns the outcome of the last Execute or Compensate call made with
+	// the idempotency key of the request.
+	Status(context.Context, *StatusRequest) (*StepResponse, error)
+	mustEmbedUnimplementedParticipantServer()
+}
+
+// UnimplementedParticipantServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedParticipantServer struct{}
+
+func (UnimplementedParticipantServer) Execute(context.Context, *StepRequest) (*StepResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Execute not implemented")
+}
+func (UnimplementedParticipantServer) Compensate(context.Context, *StepRequest) (*StepResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Compensate not implemented")
+}
+func (UnimplementedParticipantServer) Status(context.Context, *StatusRequest) (*StepResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedParticipantServer) mustEmbedUnimplementedParticipantServer() {}
+func (UnimplementedParticipantServer) testEmbeddedByValue()                     {}
+
+// UnsafeParticipantServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ParticipantServer will
+// result in compilation errors.
+type UnsafeParticipantServer interface {
+	mustEmbedUnimplementedParticipantServer()
+}
+
+func RegisterParticipantServer(s grpc.ServiceRegistrar, srv ParticipantServer) {
+	// If the following call panics, it indicates UnimplementedParticipantServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Participant_ServiceDesc, srv)
+}
+
+func _Participant_Execute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServer).Execute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Participant_Execute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServer).Execute(ctx, req.(*StepRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Participant_Compensate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServer).Compensate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Participant_Compensate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServer).Compensate(ctx, req.(*StepRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Participant_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParticipantServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Participant_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParticipantServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Participant_ServiceDesc is the grpc.ServiceDesc for Participant service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Participant_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gotx.participant.v1.Participant",
+	HandlerType: (*ParticipantServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Execute",
+			Handler:    _Participant_Execute_Handler,
+		},
+		{
+			MethodName: "Compensate",
+			Handler:    _Participant_Compensate_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Participant_Status_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "participantpb/participant.proto",
+}
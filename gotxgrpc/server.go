@@ -0,0 +1,168 @@
+package gotxgrpc
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/interwubs/goTx"
+	"github.com/interwubs/goTx/gotxgrpc/participantpb"
+)
+
+type inputCtxKey struct{}
+
+// Input returns the input of the StepRequest a served step handles.
+func Input(ctx context.Context) []byte {
+	input, _ := ctx.Value(inputCtxKey{}).([]byte)
+	return input
+}
+
+// Server implements the Participant service with local step handlers. It
+// records the outcome of every idempotency key, so a repeated Execute or
+// Compensate returns the recorded outcome instead of invoking the handler again.
+// Handlers receive the idempotency key through goTx.IdempotencyKey and the input
+// through Input.
+//
+// Handler errors, including panics, are returned in StepResponse.Error, which
+// clients do not retry. Handlers can return a gRPC status error to make the
+// call itself fail with that status instead. Context errors are returned as
+// the matching status and are not recorded, so that a call that timed out can
+// be retried with the same idempotency key.
+type Server struct {
+	participantpb.UnimplementedParticipantServer
+
+	mu       sync.Mutex
+	steps    map[string]goTx.Step
+	outcomes map[string]*participantpb.StepResponse
+}
+
+func NewServer(steps ...goTx.Step) *Server {
+	s := &Server{
+		steps:    make(map[string]goTx.Step),
+		outcomes: make(map[string]*participantpb.StepResponse),
+	}
+	for _, step := range steps {
+		s.Register(step)
+	}
+	return s
+}
+
+func (s *Server) Register(step goTx.Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.steps[step.Name] = step
+}
+
+func (s *Server) Execute(ctx context.Context, req *participantpb.StepRequest) (*participantpb.StepResponse, error) {
+	step, err := s.step(req.GetStep())
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, req, goTx.ScopeUpdate, step.Update, participantpb.StepState_STEP_STATE_SUCCEEDED, participantpb.StepState_STEP_STATE_FAILED)
+}
+
+func (s *Server) Compensate(ctx context.Context, req *participantpb.StepRequest) (*participantpb.StepResponse, error) {
+	step, err := s.step(req.GetStep())
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, req, goTx.ScopeCompensate, step.Compensate, participantpb.StepState_STEP_STATE_COMPENSATED, participantpb.StepState_STEP_STATE_COMPENSATION_FAILED)
+}
+
+func (s *Server) Status(_ context.Context, req *participantpb.StatusRequest) (*participantpb.StepResponse, error) {
+	if _, err := s.step(req.GetStep()); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scope := range []goTx.IdempotencyScope{goTx.ScopeCompensate, goTx.ScopeUpdate} {
+		if outcome, ok := s.outcomes[outcomeKey(req.GetStep(), req.GetIdempotencyKey(), scope)]; ok {
+			return outcome, nil
+		}
+	}
+	return &participantpb.StepResponse{State: participantpb.StepState_STEP_STATE_UNSPECIFIED}, nil
+}
+
+func (s *Server) step(name string) (goTx.Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	step, ok := s.steps[name]
+	if !ok {
+		return goTx.Step{}, status.Errorf(codes.NotFound, "step %q not found", name)
+	}
+	return step, nil
+}
+
+func (s *Server) run(ctx context.Context, req *participantpb.StepRequest, scope goTx.IdempotencyScope, fn goTx.StepFunc, succeeded, failed participantpb.StepState) (*participantpb.StepResponse, error) {
+	key := outcomeKey(req.GetStep(), req.GetIdempotencyKey(), scope)
+	if req.GetIdempotencyKey() != "" {
+		s.mu.Lock()
+		outcome, ok := s.outcomes[key]
+		if !ok {
+			s.outcomes[key] = &participantpb.StepResponse{State: participantpb.StepState_STEP_STATE_RUNNING}
+		}
+		s.mu.Unlock()
+
+		if ok {
+			if outcome.GetState() == participantpb.StepState_STEP_STATE_RUNNING {
+				return nil, status.Errorf(codes.Aborted, "step %q is already running for this idempotency key", req.GetStep())
+			}
+			return outcome, nil
+		}
+	}
+
+	resp := &participantpb.StepResponse{State: succeeded}
+	if fn != nil {
+		ctx = context.WithValue(ctx, inputCtxKey{}, req.GetInput())
+		ctx = goTx.WithIdempotencyKey(ctx, req.GetIdempotencyKey())
+		if err := safeStep(fn)(ctx); err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				s.forget(key)
+				return nil, status.FromContextError(err).Err()
+			}
+			if _, ok := status.FromError(err); ok {
+				s.forget(key)
+				return nil, err
+			}
+			resp = &participantpb.StepResponse{State: failed, Error: err.Error()}
+		}
+	}
+
+	if req.GetIdempotencyKey() != "" {
+		s.mu.Lock()
+		s.outcomes[key] = resp
+		s.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (s *Server) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.outcomes, key)
+}
+
+// safeStep returns fn recovering a panic as a goTx.PanicError.
+func safeStep(fn goTx.StepFunc) goTx.StepFunc {
+	return func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &goTx.PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		return fn(ctx)
+	}
+}
+
+func outcomeKey(step, idempotencyKey string, scope goTx.IdempotencyScope) string {
+	return step + "\x00" + idempotencyKey + "\x00" + string(scope)
+}
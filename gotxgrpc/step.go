@@ -0,0 +1,106 @@
+// Package gotxgrpc lets saga steps run as remote gRPC calls to participants
+// implementing the Participant service of participantpb, and exposes local step
+// handlers as such participants.
+package gotxgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative participantpb/participant.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/interwubs/goTx"
+	"github.com/interwubs/goTx/gotxgrpc/participantpb"
+)
+
+// IdempotencyKeyHeader is the metadata key carrying the idempotency key of the
+// step, in addition to the field of the request.
+const IdempotencyKeyHeader = "gotx-idempotency-key"
+
+// ErrNonRetryable wraps errors with a status code that retrying cannot fix, such
+// as InvalidArgument or FailedPrecondition.
+var ErrNonRetryable = errors.New("non-retryable participant error")
+
+type StepOptions struct {
+	// Timeout is the deadline of each call. Zero means no deadline besides the
+	// one of the step context.
+	Timeout time.Duration
+	// Retry, if set, retries failed calls. Calls failing with a non-retryable
+	// status code are not retried.
+	Retry *goTx.RetryOptions
+	// Input returns the input sent to the participant.
+	Input func(ctx context.Context) []byte
+	// CallOptions are passed to every call.
+	CallOptions []grpc.CallOption
+}
+
+// Step returns a saga step that calls Execute on the participant as its update
+// and Compensate as its compensation.
+func Step(name string, client participantpb.ParticipantClient, opts StepOptions) goTx.Step {
+	return goTx.Step{
+		Name: name,
+		Update: func(ctx context.Context) error {
+			return call(ctx, name, opts, client.Execute)
+		},
+		Compensate: func(ctx context.Context) error {
+			return call(ctx, name, opts, client.Compensate)
+		},
+	}
+}
+
+type rpc func(ctx context.Context, req *participantpb.StepRequest, opts ...grpc.CallOption) (*participantpb.StepResponse, error)
+
+func call(ctx context.Context, step string, opts StepOptions, fn rpc) error {
+	key, _ := goTx.IdempotencyKey(ctx)
+	req := &participantpb.StepRequest{Step: step, IdempotencyKey: key}
+	if opts.Input != nil {
+		req.Input = opts.Input(ctx)
+	}
+	if key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, IdempotencyKeyHeader, key)
+	}
+
+	attempt := func(ctx context.Context) error {
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+
+		resp, err := fn(ctx, req, opts.CallOptions...)
+		if err != nil {
+			if !retryable(status.Code(err)) {
+				return fmt.Errorf("%w: %w", ErrNonRetryable, err)
+			}
+			return err
+		}
+		if resp.GetError() != "" {
+			return fmt.Errorf("%w: %s", ErrNonRetryable, resp.GetError())
+		}
+		return nil
+	}
+
+	if opts.Retry == nil {
+		return attempt(ctx)
+	}
+	retry := *opts.Retry
+	retry.UnrecoverableErrors = append(append([]error(nil), retry.UnrecoverableErrors...), ErrNonRetryable)
+	return goTx.RetryContext(ctx, attempt, retry)
+}
+
+func retryable(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented, codes.Unauthenticated:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,287 @@
+package gotxgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/interwubs/goTx"
+	"github.com/interwubs/goTx/gotxgrpc/participantpb"
+)
+
+func newTestClient(t *testing.T, srv *Server) participantpb.ParticipantClient {
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	participantpb.RegisterParticipantServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return participantpb.NewParticipantClient(conn)
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return fmt.Sprint(r.calls)
+}
+
+func TestStep_Saga(t *testing.T) {
+	rec := &recorder{}
+	handler := func(name string, err error) goTx.Step {
+		return goTx.Step{
+			Name: name,
+			Update: func(ctx context.Context) error {
+				key, _ := goTx.IdempotencyKey(ctx)
+				rec.add(fmt.Sprintf("execute %s %s %s", name, key, Input(ctx)))
+				return err
+			},
+			Compensate: func(ctx context.Context) error {
+				key, _ := goTx.IdempotencyKey(ctx)
+				rec.add(fmt.Sprintf("compensate %s %s", name, key))
+				return nil
+			},
+		}
+	}
+	client := newTestClient(t, NewServer(handler("charge", nil), handler("ship", errors.New("no courier"))))
+
+	opts := StepOptions{Timeout: time.Second, Input: func(context.Context) []byte { return []byte("order-1") }}
+	def, err := goTx.NewSagaBuilder("order").
+		AppendStep(Step("charge", client, opts)).
+		AppendStep(Step("ship", client, opts)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = def.NewExecutionWithID("exec-1").Execute(context.Background())
+	if !errors.Is(err, ErrNonRetryable) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrNonRetryable)
+	}
+	want := "[execute charge exec-1/charge/update order-1 execute ship exec-1/ship/update order-1 compensate charge exec-1/charge/compensate]"
+	if rec.String() != want {
+		t.Errorf("calls = %v, want %v", rec, want)
+	}
+}
+
+func TestStep_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		timeout      time.Duration
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "transient-errors",
+			errs:         []error{status.Error(codes.Unavailable, "down"), status.Error(codes.Unavailable, "down")},
+			wantAttempts: 3,
+		},
+		{
+			name:         "non-retryable",
+			errs:         []error{status.Error(codes.InvalidArgument, "bad request")},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "deadline",
+			errs:         []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded},
+			timeout:      20 * time.Millisecond,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var (
+					mu       sync.Mutex
+					attempts int
+				)
+				srv := NewServer(goTx.Step{
+					Name: "charge",
+					Update: func(ctx context.Context) error {
+						mu.Lock()
+						attempts++
+						i := attempts
+						mu.Unlock()
+
+						if i > len(tt.errs) {
+							return nil
+						}
+						if errors.Is(tt.errs[i-1], context.DeadlineExceeded) {
+							<-ctx.Done()
+							return status.FromContextError(ctx.Err()).Err()
+						}
+						return tt.errs[i-1]
+					},
+				})
+				client := newTestClient(t, srv)
+
+				step := Step("charge", client, StepOptions{
+					Timeout: tt.timeout,
+					Retry:   &goTx.RetryOptions{MaxRetries: 3, Backoff: &goTx.ConstantBackoff{Interval: time.Millisecond}},
+				})
+				err := step.Update(context.Background())
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+			},
+		)
+	}
+}
+
+func TestServer_Idempotency(t *testing.T) {
+	calls := 0
+	headers := make(chan []string, 1)
+	srv := NewServer(goTx.Step{
+		Name: "charge",
+		Update: func(ctx context.Context) error {
+			calls++
+			md, _ := metadata.FromIncomingContext(ctx)
+			headers <- md.Get(IdempotencyKeyHeader)
+			return nil
+		},
+	})
+	client := newTestClient(t, srv)
+
+	step := Step("charge", client, StepOptions{})
+	ctx := goTx.WithIdempotencyKey(context.Background(), "exec-1/charge/update")
+	for i := 0; i < 2; i++ {
+		if err := step.Update(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+	if got := <-headers; fmt.Sprint(got) != "[exec-1/charge/update]" {
+		t.Errorf("idempotency metadata = %v, want [exec-1/charge/update]", got)
+	}
+
+	resp, err := client.Status(context.Background(), &participantpb.StatusRequest{Step: "charge", IdempotencyKey: "exec-1/charge/update"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetState() != participantpb.StepState_STEP_STATE_SUCCEEDED {
+		t.Errorf("Status() = %v, want %v", resp.GetState(), participantpb.StepState_STEP_STATE_SUCCEEDED)
+	}
+
+	if _, err := client.Execute(context.Background(), &participantpb.StepRequest{Step: "refund"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Execute(refund) error = %v, want %v", err, codes.NotFound)
+	}
+}
+
+func TestServer_IdempotencyFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      func(ctx context.Context, attempt int) error
+		wantAttempts int
+		wantErr      bool
+		wantState    participantpb.StepState
+	}{
+		{
+			name: "context error",
+			handler: func(ctx context.Context, attempt int) error {
+				if attempt == 1 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			},
+			wantAttempts: 2,
+			wantState:    participantpb.StepState_STEP_STATE_SUCCEEDED,
+		},
+		{
+			name: "panic",
+			handler: func(context.Context, int) error {
+				panic("boom")
+			},
+			wantAttempts: 1,
+			wantErr:      true,
+			wantState:    participantpb.StepState_STEP_STATE_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var (
+					mu       sync.Mutex
+					attempts int
+				)
+				srv := NewServer(goTx.Step{
+					Name: "charge",
+					Update: func(ctx context.Context) error {
+						mu.Lock()
+						attempts++
+						i := attempts
+						mu.Unlock()
+
+						return tt.handler(ctx, i)
+					},
+				})
+				client := newTestClient(t, srv)
+
+				step := Step("charge", client, StepOptions{
+					Timeout: 20 * time.Millisecond,
+					Retry:   &goTx.RetryOptions{MaxRetries: 5, Backoff: &goTx.ConstantBackoff{Interval: 10 * time.Millisecond}},
+				})
+				ctx := goTx.WithIdempotencyKey(context.Background(), "exec-1/charge/update")
+				for range 2 {
+					if err := step.Update(ctx); (err != nil) != tt.wantErr {
+						t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
+					}
+				}
+				mu.Lock()
+				if attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+				mu.Unlock()
+
+				resp, err := client.Status(context.Background(), &participantpb.StatusRequest{Step: "charge", IdempotencyKey: "exec-1/charge/update"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.GetState() != tt.wantState {
+					t.Errorf("Status() = %v, want %v", resp.GetState(), tt.wantState)
+				}
+			},
+		)
+	}
+}
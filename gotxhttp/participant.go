@@ -0,0 +1,165 @@
+package gotxhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+type inputCtxKey struct{}
+
+// WithInput returns a context carrying the JSON input of a saga execution.
+func WithInput(ctx context.Context, input json.RawMessage) context.Context {
+	return context.WithValue(ctx, inputCtxKey{}, input)
+}
+
+// Input returns the JSON input of the saga execution a step runs in.
+func Input(ctx context.Context) json.RawMessage {
+	input, _ := ctx.Value(inputCtxKey{}).(json.RawMessage)
+	return input
+}
+
+// ParticipantRequest is the body posted to the forward and compensate URLs of
+// an HTTP participant.
+type ParticipantRequest struct {
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	Step           string          `json:"step"`
+	Input          json.RawMessage `json:"input,omitempty"`
+}
+
+// HTTPStep returns a saga step whose update posts a ParticipantRequest to
+// forwardURL and whose compensation posts one to compensateURL. Any status other
+// than 2xx is a failure. The idempotency key of the step is also sent in the
+// Idempotency-Key header. compensateURL may be empty if the step needs no
+// compensation; client defaults to http.DefaultClient.
+func HTTPStep(name, forwardURL, compensateURL string, client *http.Client) goTx.Step {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	step := goTx.Step{
+		Name: name,
+		Update: func(ctx context.Context) error {
+			return post(ctx, client, forwardURL, name)
+		},
+	}
+	if compensateURL != "" {
+		step.Compensate = func(ctx context.Context) error {
+			return post(ctx, client, compensateURL, name)
+		}
+	}
+	return step
+}
+
+func post(ctx context.Context, client *http.Client, url, step string) error {
+	key, _ := goTx.IdempotencyKey(ctx)
+	body, err := json.Marshal(ParticipantRequest{IdempotencyKey: key, Step: step, Input: Input(ctx)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := ResponseError(resp); err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// StatusError is the error of an HTTP response with a status other than 2xx.
+// It implements goTx.RetryAfterError, so goTx retries wait as long as its
+// Retry-After header asks.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+	// Wait is the delay of the Retry-After header of the response, or zero if
+	// it had none.
+	Wait time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.URL, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.URL, http.StatusText(e.StatusCode), e.Body)
+}
+
+func (e *StatusError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// ResponseError returns nil if resp has a 2xx status and a *StatusError
+// otherwise, with the beginning of the body and the delay of the Retry-After
+// header, given in seconds or as an HTTP date. It reads from the body but does
+// not close it.
+func ResponseError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err := &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(bytes.TrimSpace(msg)),
+		Wait:       retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+	if resp.Request != nil {
+		err.Method = resp.Request.Method
+		err.URL = resp.Request.URL.String()
+	}
+	return err
+}
+
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// ParticipantHandler returns an http.Handler that decodes a ParticipantRequest
+// and calls fn with it. It responds 204 No Content if fn succeeds and 500 with
+// the error message otherwise.
+func ParticipantHandler(fn func(ctx context.Context, req ParticipantRequest) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ParticipantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := fn(r.Context(), req); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,151 @@
+package gotxhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+func TestResponseError(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		wantErr    bool
+		wantWait   time.Duration
+		wantMsg    string
+	}{
+		{
+			name:   "success",
+			status: http.StatusCreated,
+		},
+		{
+			name:       "retry after seconds",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "3",
+			body:       "slow down\n",
+			wantErr:    true,
+			wantWait:   3 * time.Second,
+			wantMsg:    "POST http://partner/charge: Too Many Requests: slow down",
+		},
+		{
+			name:       "retry after date",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: now.Add(time.Minute).UTC().Format(http.TimeFormat),
+			wantErr:    true,
+			wantWait:   time.Minute,
+			wantMsg:    "POST http://partner/charge: Service Unavailable",
+		},
+		{
+			name:       "retry after past date",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: now.Add(-time.Minute).UTC().Format(http.TimeFormat),
+			wantErr:    true,
+		},
+		{
+			name:       "invalid retry after",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "soon",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				if tt.retryAfter != "" {
+					rec.Header().Set("Retry-After", tt.retryAfter)
+				}
+				rec.WriteHeader(tt.status)
+				rec.WriteString(tt.body)
+				resp := rec.Result()
+				resp.Request = httptest.NewRequest(http.MethodPost, "http://partner/charge", nil)
+
+				err := ResponseError(resp)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ResponseError() = %v, wantErr %t", err, tt.wantErr)
+				}
+				if err == nil {
+					return
+				}
+
+				var rerr goTx.RetryAfterError
+				if !errors.As(err, &rerr) {
+					t.Fatalf("ResponseError() = %T, want goTx.RetryAfterError", err)
+				}
+				// HTTP dates have a resolution of one second.
+				if got := rerr.RetryAfter(); got > tt.wantWait || got < tt.wantWait-time.Second {
+					t.Errorf("RetryAfter() = %v, want %v", got, tt.wantWait)
+				}
+				if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+					t.Errorf("Error() = %q, want %q", err.Error(), tt.wantMsg)
+				}
+			},
+		)
+	}
+}
+
+type recordingClock struct {
+	mu     sync.Mutex
+	sleeps []time.Duration
+}
+
+func (c *recordingClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *recordingClock) Sleep(_ context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+	return nil
+}
+
+func TestHTTPStep_RetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	clock := &recordingClock{}
+	def, err := goTx.NewSagaBuilder("order").
+		WithRetries(goTx.RetryOptions{MaxRetries: 3, Backoff: &goTx.ConstantBackoff{Interval: time.Second}, Clock: clock}).
+		AppendStep(HTTPStep("charge", ts.URL+"/charge", "", nil)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := def.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := clock.sleeps; len(got) != 1 || got[0] != 7*time.Second {
+		t.Errorf("sleeps = %v, want [7s]", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+}
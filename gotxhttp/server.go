@@ -0,0 +1,237 @@
+// Package gotxhttp runs goTx sagas as an HTTP service and lets saga steps call
+// participants over HTTP.
+package gotxhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/interwubs/goTx"
+)
+
+var ErrSagaRegistered = errors.New("saga already registered")
+
+// Server exposes registered sagas over HTTP:
+//
+//	GET  /sagas                        names of the registered sagas
+//	POST /sagas/{name}/executions      start an execution with the JSON request body as input
+//	GET  /executions                   list executions, filtered by ?saga= and ?state=
+//	GET  /executions/{id}              report of one execution
+//
+// Executions run in the background and the POST request returns 202 Accepted
+// with the report of the new execution, unless ?wait=true is set, in which case
+// it returns the final report once the execution finished. An execution does
+// not depend on the request that started it: if that request ends first, the
+// execution goes on in the background.
+//
+// The server keeps every execution in memory unless SetMaxExecutions limits
+// their number.
+type Server struct {
+	mux *http.ServeMux
+	ctx context.Context
+	wg  sync.WaitGroup
+
+	mu         sync.Mutex
+	sagas      map[string]*goTx.SagaDefinition
+	executions map[string]*goTx.SagaExecution
+	order      []string
+
+	maxExecutions int
+}
+
+func NewServer() *Server {
+	return NewServerContext(context.Background())
+}
+
+// NewServerContext creates a Server whose background executions run with ctx.
+func NewServerContext(ctx context.Context) *Server {
+	s := &Server{
+		mux:        http.NewServeMux(),
+		ctx:        ctx,
+		sagas:      make(map[string]*goTx.SagaDefinition),
+		executions: make(map[string]*goTx.SagaExecution),
+	}
+	s.mux.HandleFunc("GET /sagas", s.listSagas)
+	s.mux.HandleFunc("POST /sagas/{name}/executions", s.startExecution)
+	s.mux.HandleFunc("GET /executions", s.listExecutions)
+	s.mux.HandleFunc("GET /executions/{id}", s.getExecution)
+	return s
+}
+
+func (s *Server) Register(def *goTx.SagaDefinition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sagas[def.Name()]; ok {
+		return fmt.Errorf("%w: %q", ErrSagaRegistered, def.Name())
+	}
+	s.sagas[def.Name()] = def
+	return nil
+}
+
+// SetMaxExecutions makes the server keep at most n executions. When a new
+// execution exceeds the limit, the oldest executions that succeeded, were
+// compensated or failed are forgotten; running and suspended executions are
+// kept even if that exceeds the limit. A limit of 0 keeps all executions.
+func (s *Server) SetMaxExecutions(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxExecutions = n
+	s.forgetFinished()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Wait blocks until all executions started in the background have finished.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Execution returns the execution with the given ID.
+func (s *Server) Execution(id string) (*goTx.SagaExecution, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exec, ok := s.executions[id]
+	return exec, ok
+}
+
+func (s *Server) listSagas(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.sagas))
+	for name := range s.sagas {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) startExecution(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	def, ok := s.sagas[r.PathValue("name")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("saga %q not found", r.PathValue("name")))
+		return
+	}
+
+	input, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(input) == 0 {
+		input = []byte("null")
+	}
+	if !json.Valid(input) {
+		writeError(w, http.StatusBadRequest, errors.New("request body is not valid JSON"))
+		return
+	}
+
+	exec := def.NewExecution()
+	s.mu.Lock()
+	s.executions[exec.ID()] = exec
+	s.order = append(s.order, exec.ID())
+	s.forgetFinished()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(done)
+		_ = exec.Execute(WithInput(s.ctx, input))
+	}()
+
+	if r.URL.Query().Get("wait") == "true" {
+		select {
+		case <-done:
+			writeJSON(w, http.StatusOK, exec.Report())
+			return
+		case <-r.Context().Done():
+		}
+	}
+	writeJSON(w, http.StatusAccepted, exec.Report())
+}
+
+// forgetFinished forgets the oldest finished executions beyond the limit set
+// with SetMaxExecutions. s.mu must be held.
+func (s *Server) forgetFinished() {
+	excess := len(s.order) - s.maxExecutions
+	if s.maxExecutions <= 0 || excess <= 0 {
+		return
+	}
+
+	kept := s.order[:0]
+	for _, id := range s.order {
+		switch s.executions[id].State() {
+		case goTx.ExecutionSucceeded, goTx.ExecutionCompensated, goTx.ExecutionFailed:
+			if excess > 0 {
+				delete(s.executions, id)
+				excess--
+				continue
+			}
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func (s *Server) listExecutions(w http.ResponseWriter, r *http.Request) {
+	saga := r.URL.Query().Get("saga")
+	var state *goTx.ExecutionState
+	if v := r.URL.Query().Get("state"); v != "" {
+		state = new(goTx.ExecutionState)
+		if err := state.UnmarshalText([]byte(v)); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	execs := make([]*goTx.SagaExecution, 0, len(s.order))
+	for _, id := range s.order {
+		execs = append(execs, s.executions[id])
+	}
+	s.mu.Unlock()
+
+	reports := make([]*goTx.ExecutionReport, 0, len(execs))
+	for _, exec := range execs {
+		report := exec.Report()
+		if (saga != "" && report.Saga != saga) || (state != nil && report.State != *state) {
+			continue
+		}
+		reports = append(reports, report)
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+func (s *Server) getExecution(w http.ResponseWriter, r *http.Request) {
+	exec, ok := s.Execution(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("execution %q not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, exec.Report())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,297 @@
+package gotxhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+type participant struct {
+	mu       sync.Mutex
+	requests []string
+	fail     bool
+	server   *httptest.Server
+}
+
+func newParticipant(t *testing.T, fail bool) *participant {
+	p := &participant{fail: fail}
+	mux := http.NewServeMux()
+	for _, action := range []string{"forward", "compensate"} {
+		action := action
+		mux.Handle("/"+action, ParticipantHandler(func(_ context.Context, req ParticipantRequest) error {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			p.requests = append(p.requests, fmt.Sprintf("%s %s %t %s", action, req.Step, req.IdempotencyKey != "", req.Input))
+			if p.fail && action == "forward" {
+				return errors.New("out of stock")
+			}
+			return nil
+		}))
+	}
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *participant) step(name string) goTx.Step {
+	return HTTPStep(name, p.server.URL+"/forward", p.server.URL+"/compensate", p.server.Client())
+}
+
+func (p *participant) log() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.requests...)
+}
+
+func newTestServer(t *testing.T, failInventory bool) (*httptest.Server, *Server, *participant, *participant) {
+	payments := newParticipant(t, false)
+	inventory := newParticipant(t, failInventory)
+
+	def, err := goTx.NewSagaBuilder("order").
+		AppendStep(payments.step("charge")).
+		AppendStep(inventory.step("reserve")).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := NewServer()
+	if err := srv.Register(def); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Register(def); !errors.Is(err, ErrSagaRegistered) {
+		t.Errorf("Register() twice error = %v, want %v", err, ErrSagaRegistered)
+	}
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts, srv, payments, inventory
+}
+
+func doJSON(t *testing.T, method, url, body string, v any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_StartExecution(t *testing.T) {
+	tests := []struct {
+		name          string
+		failInventory bool
+		wantState     goTx.ExecutionState
+		wantPayments  []string
+		wantInventory []string
+	}{
+		{
+			name:          "happypath",
+			wantState:     goTx.ExecutionSucceeded,
+			wantPayments:  []string{`forward charge true {"order":1}`},
+			wantInventory: []string{`forward reserve true {"order":1}`},
+		},
+		{
+			name:          "participant-fails",
+			failInventory: true,
+			wantState:     goTx.ExecutionCompensated,
+			wantPayments:  []string{`forward charge true {"order":1}`, `compensate charge true {"order":1}`},
+			wantInventory: []string{`forward reserve true {"order":1}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ts, _, payments, inventory := newTestServer(t, tt.failInventory)
+
+				var report goTx.ExecutionReport
+				if status := doJSON(t, http.MethodPost, ts.URL+"/sagas/order/executions?wait=true", `{"order":1}`, &report); status != http.StatusOK {
+					t.Fatalf("POST status = %d, want %d", status, http.StatusOK)
+				}
+				if report.State != tt.wantState {
+					t.Errorf("State = %v, want %v", report.State, tt.wantState)
+				}
+				if got := payments.log(); fmt.Sprint(got) != fmt.Sprint(tt.wantPayments) {
+					t.Errorf("payments requests = %q, want %q", got, tt.wantPayments)
+				}
+				if got := inventory.log(); fmt.Sprint(got) != fmt.Sprint(tt.wantInventory) {
+					t.Errorf("inventory requests = %q, want %q", got, tt.wantInventory)
+				}
+
+				var stored goTx.ExecutionReport
+				if status := doJSON(t, http.MethodGet, ts.URL+"/executions/"+report.ID, "", &stored); status != http.StatusOK {
+					t.Fatalf("GET status = %d, want %d", status, http.StatusOK)
+				}
+				if stored.ID != report.ID || stored.State != tt.wantState || len(stored.Steps) != 2 {
+					t.Errorf("GET /executions/%s = %+v", report.ID, stored)
+				}
+				if tt.failInventory {
+					reserve, _ := stored.Step("reserve")
+					if reserve.Status != goTx.StepFailed || !strings.Contains(reserve.Err.Error(), "out of stock") {
+						t.Errorf("reserve = %+v, want failed with participant error", reserve)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestServer_Background(t *testing.T) {
+	ts, srv, _, _ := newTestServer(t, false)
+
+	var started goTx.ExecutionReport
+	if status := doJSON(t, http.MethodPost, ts.URL+"/sagas/order/executions", `{"order":2}`, &started); status != http.StatusAccepted {
+		t.Fatalf("POST status = %d, want %d", status, http.StatusAccepted)
+	}
+	doJSON(t, http.MethodPost, ts.URL+"/sagas/order/executions?wait=true", `{"order":3}`, nil)
+	srv.Wait()
+
+	var reports []goTx.ExecutionReport
+	doJSON(t, http.MethodGet, ts.URL+"/executions?saga=order&state=succeeded", "", &reports)
+	if len(reports) != 2 || reports[0].ID != started.ID {
+		t.Errorf("GET /executions = %+v, want 2 succeeded executions starting with %q", reports, started.ID)
+	}
+	doJSON(t, http.MethodGet, ts.URL+"/executions?state=compensated", "", &reports)
+	if len(reports) != 0 {
+		t.Errorf("GET /executions?state=compensated = %+v, want none", reports)
+	}
+
+	var sagas []string
+	doJSON(t, http.MethodGet, ts.URL+"/sagas", "", &sagas)
+	if fmt.Sprint(sagas) != "[order]" {
+		t.Errorf("GET /sagas = %v, want [order]", sagas)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ts, _, _, _ := newTestServer(t, false)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown-saga", method: http.MethodPost, path: "/sagas/refund/executions", body: "{}", wantStatus: http.StatusNotFound},
+		{name: "invalid-input", method: http.MethodPost, path: "/sagas/order/executions", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "unknown-execution", method: http.MethodGet, path: "/executions/nope", wantStatus: http.StatusNotFound},
+		{name: "invalid-state", method: http.MethodGet, path: "/executions?state=sleeping", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var body map[string]string
+				if status := doJSON(t, tt.method, ts.URL+tt.path, tt.body, &body); status != tt.wantStatus {
+					t.Errorf("status = %d, want %d", status, tt.wantStatus)
+				}
+				if body["error"] == "" {
+					t.Errorf("body = %v, want an error message", body)
+				}
+			},
+		)
+	}
+}
+
+func TestServer_WaitRequestEnds(t *testing.T) {
+	release := make(chan struct{})
+	def, err := goTx.NewSagaBuilder("order").
+		AppendStep(goTx.Step{Name: "charge", Update: func(ctx context.Context) error {
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer()
+	if err := srv.Register(def); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/sagas/order/executions?wait=true", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errc <- err
+	}()
+	for len(srv.executionIDs()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("POST error = %v, want %v", err, context.Canceled)
+	}
+
+	// Close waits for the handler, which returns once the request ended.
+	ts.Close()
+	close(release)
+	srv.Wait()
+	exec, _ := srv.Execution(srv.executionIDs()[0])
+	if exec.State() != goTx.ExecutionSucceeded {
+		t.Errorf("State = %v, want %v", exec.State(), goTx.ExecutionSucceeded)
+	}
+}
+
+func TestServer_MaxExecutions(t *testing.T) {
+	ts, srv, _, _ := newTestServer(t, false)
+	srv.SetMaxExecutions(2)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		var report goTx.ExecutionReport
+		doJSON(t, http.MethodPost, ts.URL+"/sagas/order/executions?wait=true", `{"order":1}`, &report)
+		ids = append(ids, report.ID)
+	}
+	srv.Wait()
+
+	if got := srv.executionIDs(); fmt.Sprint(got) != fmt.Sprint(ids[1:]) {
+		t.Errorf("executions = %v, want %v", got, ids[1:])
+	}
+	if status := doJSON(t, http.MethodGet, ts.URL+"/executions/"+ids[0], "", nil); status != http.StatusNotFound {
+		t.Errorf("GET forgotten execution status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+// executionIDs returns the IDs of the executions the server keeps, oldest
+// first.
+func (s *Server) executionIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.order...)
+}
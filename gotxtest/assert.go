@@ -0,0 +1,68 @@
+package gotxtest
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/interwubs/goTx"
+)
+
+// CompensationOrder returns the steps of report that were compensated, in the
+// order their compensations started.
+func CompensationOrder(report *goTx.ExecutionReport) []string {
+	var indexes []int
+	for i, step := range report.Steps {
+		if step.Status == goTx.StepCompensated {
+			indexes = append(indexes, i)
+		}
+	}
+	// Compensations that started within the clock resolution ran in reverse
+	// order of their steps.
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := report.Steps[indexes[i]], report.Steps[indexes[j]]
+		if !a.CompensationStartedAt.Equal(b.CompensationStartedAt) {
+			return a.CompensationStartedAt.Before(b.CompensationStartedAt)
+		}
+		return indexes[i] > indexes[j]
+	})
+
+	steps := make([]string, len(indexes))
+	for i, index := range indexes {
+		steps[i] = report.Steps[index].Name
+	}
+	return steps
+}
+
+// AssertCompensated reports an error unless exactly the given steps of report
+// were compensated, in this order.
+func AssertCompensated(t testing.TB, report *goTx.ExecutionReport, steps ...string) {
+	t.Helper()
+
+	if got := CompensationOrder(report); !slices.Equal(got, steps) {
+		t.Errorf("saga %q compensated %v, want %v", report.Saga, got, steps)
+	}
+}
+
+// AssertState reports an error unless the execution of report is in state.
+func AssertState(t testing.TB, report *goTx.ExecutionReport, state goTx.ExecutionState) {
+	t.Helper()
+
+	if report.State != state {
+		t.Errorf("saga %q is %s, want %s (error: %v)", report.Saga, report.State, state, report.Err)
+	}
+}
+
+// AssertStepStatus reports an error unless step of report has status.
+func AssertStepStatus(t testing.TB, report *goTx.ExecutionReport, step string, status goTx.StepStatus) {
+	t.Helper()
+
+	got, ok := report.Step(step)
+	if !ok {
+		t.Errorf("saga %q has no step %q", report.Saga, step)
+		return
+	}
+	if got.Status != status {
+		t.Errorf("step %q of saga %q is %s, want %s", step, report.Saga, got.Status, status)
+	}
+}
@@ -0,0 +1,150 @@
+package gotxtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/interwubs/goTx"
+)
+
+// FailurePoint is the failure Explore injects into one run of a saga.
+type FailurePoint struct {
+	// FailStep is the step whose update fails, if any.
+	FailStep string
+	// FailCompensation is the step whose compensation fails, if any. It is
+	// only set together with a later FailStep.
+	FailCompensation string
+	// CrashBefore is the step before which the process crashes, if any. The
+	// execution is then restored from its report and resumed.
+	CrashBefore string
+}
+
+func (p FailurePoint) String() string {
+	switch {
+	case p.CrashBefore != "":
+		return fmt.Sprintf("crash before %s", p.CrashBefore)
+	case p.FailCompensation != "":
+		return fmt.Sprintf("%s fails, compensation of %s fails", p.FailStep, p.FailCompensation)
+	case p.FailStep != "":
+		return fmt.Sprintf("%s fails", p.FailStep)
+	default:
+		return "no failure"
+	}
+}
+
+// Invariant checks the outcome of one run of Explore. The state the saga works
+// on is reachable through the closure that created the invariant.
+type Invariant func(t *testing.T, point FailurePoint, report *goTx.ExecutionReport)
+
+// FailurePoints returns the failure points of def, in the order Explore runs
+// them: no failure, the update of each step failing, the compensation of each
+// step failing for every later step that fails, and a crash before each step
+// but the first. Steps of nested sagas are included and are expected to have
+// names that are unique within def.
+func FailurePoints(def *goTx.SagaDefinition) []FailurePoint {
+	var steps []goTx.Step
+	// WrapSteps visits the steps of nested sagas too.
+	def.WrapSteps(func(step goTx.Step) goTx.Step {
+		if step.Update != nil {
+			steps = append(steps, step)
+		}
+		return step
+	})
+
+	points := []FailurePoint{{}}
+	for _, step := range steps {
+		points = append(points, FailurePoint{FailStep: step.Name})
+	}
+	for i, step := range steps {
+		if step.Compensate == nil {
+			continue
+		}
+		for _, failing := range steps[i+1:] {
+			points = append(points, FailurePoint{FailStep: failing.Name, FailCompensation: step.Name})
+		}
+	}
+	for _, step := range steps[min(1, len(steps)):] {
+		points = append(points, FailurePoint{CrashBefore: step.Name})
+	}
+	return points
+}
+
+// Explore runs a saga once for every failure point returned by FailurePoints,
+// each run in its own subtest, and checks the invariants after each run. setup
+// is called before every run and returns the definition to run, so that every
+// run starts from fresh state.
+//
+// A crash is simulated by ending the goroutine running the execution when the
+// update of the step is called; the execution is then restored with
+// RestoreExecution from its report, encoded to JSON and back as a store would,
+// and resumed.
+func Explore(t *testing.T, setup func(t *testing.T) *goTx.SagaDefinition, invariants ...Invariant) {
+	t.Helper()
+
+	for _, point := range FailurePoints(setup(t)) {
+		t.Run(
+			point.String(), func(t *testing.T) {
+				def := setup(t)
+				report, err := explore(def, point)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, invariant := range invariants {
+					invariant(t, point, report)
+				}
+			},
+		)
+	}
+}
+
+func explore(def *goTx.SagaDefinition, point FailurePoint) (*goTx.ExecutionReport, error) {
+	ctx := context.Background()
+
+	if point.CrashBefore == "" {
+		in := NewInjector(0)
+		if point.FailStep != "" {
+			in.FailStep(point.FailStep)
+		}
+		if point.FailCompensation != "" {
+			in.FailCompensation(point.FailCompensation)
+		}
+		exec := in.Definition(def).NewExecution()
+		exec.Execute(ctx)
+		return exec.Report(), nil
+	}
+
+	crashing := def.WrapSteps(func(step goTx.Step) goTx.Step {
+		if step.Name == point.CrashBefore && step.Update != nil {
+			step.Update = func(context.Context) error {
+				runtime.Goexit()
+				return nil
+			}
+		}
+		return step
+	})
+	exec := crashing.NewExecution()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exec.Execute(ctx)
+	}()
+	<-done
+
+	data, err := json.Marshal(exec.Report())
+	if err != nil {
+		return nil, err
+	}
+	var report goTx.ExecutionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	restored, err := def.RestoreExecution(&report)
+	if err != nil {
+		return nil, fmt.Errorf("restoring crashed execution: %w", err)
+	}
+	restored.Resume(ctx)
+	return restored.Report(), nil
+}
@@ -0,0 +1,135 @@
+package gotxtest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/interwubs/goTx"
+)
+
+type bank struct {
+	mu       sync.Mutex
+	balances map[string]int
+}
+
+func (b *bank) add(account string, amount int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.balances[account]+amount < 0 {
+		return errors.New("insufficient funds")
+	}
+	b.balances[account] += amount
+	return nil
+}
+
+func (b *bank) total() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	for _, balance := range b.balances {
+		total += balance
+	}
+	return total
+}
+
+func transferMoney(t *testing.T, b *bank, from, to string, amount int) *goTx.SagaDefinition {
+	t.Helper()
+
+	def, err := goTx.NewSagaBuilder("transfer").
+		AppendStep(
+			goTx.Step{
+				Name:       "withdraw",
+				Update:     func(context.Context) error { return b.add(from, -amount) },
+				Compensate: func(context.Context) error { return b.add(from, amount) },
+			},
+		).
+		AppendStep(
+			goTx.Step{
+				Name:       "deposit",
+				Update:     func(context.Context) error { return b.add(to, amount) },
+				Compensate: func(context.Context) error { return b.add(to, -amount) },
+			},
+		).
+		AppendStep(
+			goTx.Step{
+				Name:   "notify",
+				Update: func(context.Context) error { return nil },
+			},
+		).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+func TestFailurePoints(t *testing.T) {
+	def := transferMoney(t, &bank{}, "alice", "bob", 1)
+
+	var got []string
+	for _, point := range FailurePoints(def) {
+		got = append(got, point.String())
+	}
+	want := []string{
+		"no failure",
+		"withdraw fails",
+		"deposit fails",
+		"notify fails",
+		"deposit fails, compensation of withdraw fails",
+		"notify fails, compensation of withdraw fails",
+		"notify fails, compensation of deposit fails",
+		"crash before deposit",
+		"crash before notify",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("FailurePoints() = %q, want %q", got, want)
+	}
+}
+
+func TestExplore(t *testing.T) {
+	var b *bank
+	setup := func(t *testing.T) *goTx.SagaDefinition {
+		b = &bank{balances: map[string]int{"alice": 100, "bob": 50}}
+		return transferMoney(t, b, "alice", "bob", 30)
+	}
+
+	var runs []string
+	Explore(
+		t, setup,
+		func(t *testing.T, point FailurePoint, report *goTx.ExecutionReport) {
+			runs = append(runs, point.String())
+		},
+		func(t *testing.T, point FailurePoint, report *goTx.ExecutionReport) {
+			// A failed compensation leaves the transfer half done.
+			if report.State == goTx.ExecutionFailed {
+				return
+			}
+			if got := b.total(); got != 150 {
+				t.Errorf("balances sum to %d, want 150", got)
+			}
+		},
+		func(t *testing.T, point FailurePoint, report *goTx.ExecutionReport) {
+			switch {
+			case point.FailCompensation != "":
+				AssertState(t, report, goTx.ExecutionFailed)
+				AssertStepStatus(t, report, point.FailCompensation, goTx.StepCompensationFailed)
+			case point.FailStep != "":
+				AssertState(t, report, goTx.ExecutionCompensated)
+			default:
+				AssertState(t, report, goTx.ExecutionSucceeded)
+				if got := b.balances["bob"]; got != 80 {
+					t.Errorf("bob has %d, want 80", got)
+				}
+			}
+		},
+	)
+
+	if len(runs) != len(FailurePoints(setup(t))) {
+		t.Errorf("Explore() checked %d runs, want one per failure point", len(runs))
+	}
+}
@@ -0,0 +1,247 @@
+// Package gotxtest injects faults into saga steps and checks the resulting
+// execution reports, to test that the compensations of a saga work.
+package gotxtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+var ErrInjected = errors.New("injected fault")
+
+// Target is the part of a step a fault is injected into.
+type Target int
+
+const (
+	Update Target = iota
+	Compensation
+)
+
+func (t Target) String() string {
+	if t == Compensation {
+		return "compensation"
+	}
+	return "update"
+}
+
+// Fault describes a fault injected into the calls of a step.
+//
+// A fault with only a Delay slows the calls down but still invokes them. A
+// fault with an Err returns an error wrapping Err after the delay instead of
+// invoking the call, and a fault with Panic set panics instead.
+type Fault struct {
+	// Step is the name of the step; empty matches every step.
+	Step   string
+	Target Target
+	// Attempt, if set, restricts the fault to the Kth call of the target,
+	// counting from 1. Otherwise every call is affected.
+	Attempt int
+	// Probability, if set, makes the fault affect a call only with that
+	// probability, drawn from the seeded random source of the Injector.
+	Probability float64
+
+	Delay time.Duration
+	Err   error
+	Panic bool
+}
+
+// Call records a call to an update or compensation wrapped by an Injector.
+type Call struct {
+	Step    string
+	Target  Target
+	Attempt int
+	// Err is the error the call returned, injected or not.
+	Err      error
+	Injected bool
+}
+
+// Injector wraps saga steps to inject faults into them and records their calls.
+// It is safe for concurrent use; random faults are reproducible for a given
+// seed as long as the steps run in the same order.
+type Injector struct {
+	mu       sync.Mutex
+	rnd      *rand.Rand
+	faults   []Fault
+	attempts map[callKey]int
+	calls    []Call
+}
+
+type callKey struct {
+	step   string
+	target Target
+}
+
+func NewInjector(seed int64) *Injector {
+	return &Injector{
+		rnd:      rand.New(rand.NewSource(seed)),
+		attempts: make(map[callKey]int),
+	}
+}
+
+// Inject adds a fault. When several faults match a call, the first one added
+// that affects it wins.
+func (in *Injector) Inject(f Fault) *Injector {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.faults = append(in.faults, f)
+	return in
+}
+
+// FailStep makes every attempt of the update of step fail with ErrInjected.
+func (in *Injector) FailStep(step string) *Injector {
+	return in.Inject(Fault{Step: step, Err: ErrInjected})
+}
+
+// FailAttempt makes the kth attempt of the update of step fail with
+// ErrInjected.
+func (in *Injector) FailAttempt(step string, k int) *Injector {
+	return in.Inject(Fault{Step: step, Attempt: k, Err: ErrInjected})
+}
+
+// FailCompensation makes every attempt of the compensation of step fail with
+// ErrInjected.
+func (in *Injector) FailCompensation(step string) *Injector {
+	return in.Inject(Fault{Step: step, Target: Compensation, Err: ErrInjected})
+}
+
+// Delay makes every attempt of the update of step wait d before it runs.
+func (in *Injector) Delay(step string, d time.Duration) *Injector {
+	return in.Inject(Fault{Step: step, Delay: d})
+}
+
+// Panic makes the update of step panic.
+func (in *Injector) Panic(step string) *Injector {
+	return in.Inject(Fault{Step: step, Panic: true})
+}
+
+// FailRandomly makes every update fail with ErrInjected with probability p.
+func (in *Injector) FailRandomly(p float64) *Injector {
+	return in.Inject(Fault{Probability: p, Err: ErrInjected})
+}
+
+// Definition returns a copy of def, with the same options, whose steps,
+// including those of nested sagas, are wrapped by the injector.
+func (in *Injector) Definition(def *goTx.SagaDefinition) *goTx.SagaDefinition {
+	return def.WrapSteps(in.Step)
+}
+
+// Step returns step with its update and compensation wrapped by the injector.
+func (in *Injector) Step(step goTx.Step) goTx.Step {
+	if step.Update != nil {
+		step.Update = in.Func(step.Name, Update, step.Update)
+	}
+	if step.Compensate != nil {
+		step.Compensate = in.Func(step.Name, Compensation, step.Compensate)
+	}
+	return step
+}
+
+// UpdateFunc wraps the update of a SagaTx step, named step for the faults.
+func (in *Injector) UpdateFunc(step string, fn goTx.UpdateFunc) goTx.UpdateFunc {
+	wrapped := in.Func(step, Update, func(context.Context) error { return fn() })
+	return func() error { return wrapped(context.Background()) }
+}
+
+// CompensateFunc wraps the compensation of a SagaTx step, named step for the
+// faults.
+func (in *Injector) CompensateFunc(step string, fn goTx.CompensateFunc) goTx.CompensateFunc {
+	wrapped := in.Func(step, Compensation, func(context.Context) error { return fn() })
+	return func() error { return wrapped(context.Background()) }
+}
+
+// Func wraps the target of step implemented by fn.
+func (in *Injector) Func(step string, target Target, fn goTx.StepFunc) goTx.StepFunc {
+	return func(ctx context.Context) error {
+		attempt, fault, ok := in.match(step, target)
+
+		var err error
+		if ok {
+			err = inject(ctx, step, target, attempt, fault)
+		}
+		injected := err != nil
+		if !injected {
+			err = fn(ctx)
+		}
+
+		in.mu.Lock()
+		in.calls = append(in.calls, Call{Step: step, Target: target, Attempt: attempt, Err: err, Injected: injected})
+		in.mu.Unlock()
+		return err
+	}
+}
+
+func (in *Injector) match(step string, target Target) (int, Fault, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	key := callKey{step: step, target: target}
+	in.attempts[key]++
+	attempt := in.attempts[key]
+
+	for _, f := range in.faults {
+		if f.Step != "" && f.Step != step || f.Target != target {
+			continue
+		}
+		if f.Attempt != 0 && f.Attempt != attempt {
+			continue
+		}
+		if f.Probability != 0 && in.rnd.Float64() >= f.Probability {
+			continue
+		}
+		return attempt, f, true
+	}
+	return attempt, Fault{}, false
+}
+
+func inject(ctx context.Context, step string, target Target, attempt int, f Fault) error {
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if f.Panic {
+		panic(fmt.Sprintf("gotxtest: injected panic in %s of step %q, attempt %d", target, step, attempt))
+	}
+	if f.Err != nil {
+		return fmt.Errorf("%w: %s of step %q, attempt %d", f.Err, target, step, attempt)
+	}
+	return nil
+}
+
+// Calls returns the calls made so far, in the order they completed.
+func (in *Injector) Calls() []Call {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return append([]Call(nil), in.calls...)
+}
+
+// Attempts returns how many times the target of step was called.
+func (in *Injector) Attempts(step string, target Target) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return in.attempts[callKey{step: step, target: target}]
+}
+
+// NthStep returns the name of the nth step of def, counting from 1, for faults
+// that target a step by position. It panics if def has fewer steps.
+func NthStep(def *goTx.SagaDefinition, n int) string {
+	steps := def.Steps()
+	if n < 1 || n > len(steps) {
+		panic(fmt.Sprintf("gotxtest: saga %q has no step %d", def.Name(), n))
+	}
+	return steps[n-1].Name
+}
@@ -0,0 +1,288 @@
+package gotxtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+type stepLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *stepLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+}
+
+func (l *stepLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return fmt.Sprint(l.entries)
+}
+
+func orderSaga(t *testing.T, log *stepLog, options ...func(b *goTx.SagaBuilder)) *goTx.SagaDefinition {
+	t.Helper()
+
+	b := goTx.NewSagaBuilder("order")
+	for _, name := range []string{"reserve", "charge", "ship"} {
+		b.AppendStep(
+			goTx.Step{
+				Name:       name,
+				Update:     func(context.Context) error { log.add(name); return nil },
+				Compensate: func(context.Context) error { log.add("undo " + name); return nil },
+			},
+		)
+	}
+	for _, option := range options {
+		option(b)
+	}
+	def, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+func TestInjector(t *testing.T) {
+	retries := func(b *goTx.SagaBuilder) {
+		b.WithRetries(goTx.RetryOptions{MaxRetries: 2, Backoff: &goTx.ConstantBackoff{}})
+	}
+
+	tests := []struct {
+		name            string
+		options         []func(b *goTx.SagaBuilder)
+		inject          func(in *Injector, def *goTx.SagaDefinition)
+		wantState       goTx.ExecutionState
+		wantCompensated []string
+		wantLog         string
+	}{
+		{
+			name:            "no faults",
+			inject:          func(*Injector, *goTx.SagaDefinition) {},
+			wantState:       goTx.ExecutionSucceeded,
+			wantCompensated: nil,
+			wantLog:         "[reserve charge ship]",
+		},
+		{
+			name:            "fail step",
+			inject:          func(in *Injector, _ *goTx.SagaDefinition) { in.FailStep("ship") },
+			wantState:       goTx.ExecutionCompensated,
+			wantCompensated: []string{"charge", "reserve"},
+			wantLog:         "[reserve charge undo charge undo reserve]",
+		},
+		{
+			name:            "fail nth step",
+			inject:          func(in *Injector, def *goTx.SagaDefinition) { in.FailStep(NthStep(def, 2)) },
+			wantState:       goTx.ExecutionCompensated,
+			wantCompensated: []string{"reserve"},
+			wantLog:         "[reserve undo reserve]",
+		},
+		{
+			name:            "fail first attempt with retries",
+			options:         []func(b *goTx.SagaBuilder){retries},
+			inject:          func(in *Injector, _ *goTx.SagaDefinition) { in.FailAttempt("charge", 1) },
+			wantState:       goTx.ExecutionSucceeded,
+			wantCompensated: nil,
+			wantLog:         "[reserve charge ship]",
+		},
+		{
+			name:            "fail compensation",
+			inject:          func(in *Injector, _ *goTx.SagaDefinition) { in.FailStep("ship").FailCompensation("reserve") },
+			wantState:       goTx.ExecutionFailed,
+			wantCompensated: []string{"charge"},
+			wantLog:         "[reserve charge undo charge]",
+		},
+		{
+			name: "latency",
+			inject: func(in *Injector, _ *goTx.SagaDefinition) {
+				in.Delay("charge", time.Millisecond)
+			},
+			wantState:       goTx.ExecutionSucceeded,
+			wantCompensated: nil,
+			wantLog:         "[reserve charge ship]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				log := &stepLog{}
+				def := orderSaga(t, log, tt.options...)
+				in := NewInjector(1)
+				tt.inject(in, def)
+
+				exec := in.Definition(def).NewExecution()
+				err := exec.Execute(context.Background())
+				if (err != nil) != (tt.wantState != goTx.ExecutionSucceeded) {
+					t.Errorf("Execute() error = %v", err)
+				}
+				if err != nil && !errors.Is(err, ErrInjected) && !strings.Contains(err.Error(), ErrInjected.Error()) {
+					t.Errorf("Execute() error = %v, want %v", err, ErrInjected)
+				}
+
+				report := exec.Report()
+				AssertState(t, report, tt.wantState)
+				AssertCompensated(t, report, tt.wantCompensated...)
+				if got := log.String(); got != tt.wantLog {
+					t.Errorf("log = %q, want %q", got, tt.wantLog)
+				}
+			},
+		)
+	}
+}
+
+func TestInjector_Calls(t *testing.T) {
+	def := orderSaga(t, &stepLog{}, func(b *goTx.SagaBuilder) {
+		b.WithRetries(goTx.RetryOptions{MaxRetries: 3, Backoff: &goTx.ConstantBackoff{}})
+	})
+	in := NewInjector(1).FailAttempt("charge", 1).FailAttempt("charge", 2)
+
+	if _, err := in.Definition(def).Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if got := in.Attempts("charge", Update); got != 3 {
+		t.Errorf("Attempts(charge) = %d, want 3", got)
+	}
+	var got []string
+	for _, call := range in.Calls() {
+		got = append(got, fmt.Sprintf("%s %s %d %t", call.Step, call.Target, call.Attempt, call.Injected))
+	}
+	want := []string{
+		"reserve update 1 false",
+		"charge update 1 true",
+		"charge update 2 true",
+		"charge update 3 false",
+		"ship update 1 false",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Calls() = %q, want %q", got, want)
+	}
+}
+
+func TestInjector_FailRandomly(t *testing.T) {
+	run := func(seed int64) string {
+		log := &stepLog{}
+		def := orderSaga(t, log)
+		exec := NewInjector(seed).FailRandomly(0.5).Definition(def).NewExecution()
+		exec.Execute(context.Background())
+		return log.String()
+	}
+
+	outcomes := make(map[string]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		got := run(seed)
+		if again := run(seed); again != got {
+			t.Fatalf("seed %d: log = %q, then %q", seed, got, again)
+		}
+		outcomes[got] = true
+	}
+	if len(outcomes) < 2 {
+		t.Errorf("20 seeds gave outcomes %v, want different outcomes", outcomes)
+	}
+}
+
+func TestInjector_Panic(t *testing.T) {
+	in := NewInjector(1).Panic("charge")
+	update := in.UpdateFunc("charge", func() error { return nil })
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), `step "charge"`) {
+			t.Errorf("recover() = %v, want injected panic", r)
+		}
+	}()
+	update()
+	t.Error("update returned, want panic")
+}
+
+func TestInjector_PanicInSaga(t *testing.T) {
+	log := &stepLog{}
+	exec := NewInjector(1).Panic("ship").Definition(orderSaga(t, log)).NewExecution()
+
+	var perr *goTx.PanicError
+	if err := exec.Execute(context.Background()); !errors.As(err, &perr) {
+		t.Errorf("Execute() error = %v, want PanicError", err)
+	}
+	AssertState(t, exec.Report(), goTx.ExecutionCompensated)
+	AssertCompensated(t, exec.Report(), "charge", "reserve")
+}
+
+func TestInjector_SagaTx(t *testing.T) {
+	log := &stepLog{}
+	in := NewInjector(1).FailCompensation("step 1")
+
+	tx := goTx.NewSagaTx(false)
+	tx.SetDeadLetterStore(goTx.NewMemoryDeadLetterStore())
+	tx.Append(
+		in.UpdateFunc("step 1", func() error { log.add("1"); return nil }),
+		in.CompensateFunc("step 1", func() error { log.add("undo 1"); return nil }),
+	)
+	tx.Append(
+		in.UpdateFunc("step 2", func() error { return errors.New("out of stock") }),
+		in.CompensateFunc("step 2", func() error { log.add("undo 2"); return nil }),
+	)
+
+	if err := tx.ExecuteAll(); err == nil || !strings.Contains(err.Error(), ErrInjected.Error()) {
+		t.Errorf("ExecuteAll() error = %v, want %v", err, ErrInjected)
+	}
+	if got, want := log.String(), "[1 undo 2]"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+	if got := in.Attempts("step 1", Compensation); got != 1 {
+		t.Errorf("Attempts(step 1, compensation) = %d, want 1", got)
+	}
+}
+
+func TestCompensationOrder(t *testing.T) {
+	start := time.Now()
+	report := &goTx.ExecutionReport{
+		Saga: "order",
+		Steps: []goTx.StepReport{
+			{Name: "a", Status: goTx.StepCompensated, CompensationStartedAt: start.Add(time.Second)},
+			{Name: "b", Status: goTx.StepCompensated, CompensationStartedAt: start},
+			{Name: "c", Status: goTx.StepCompensated, CompensationStartedAt: start},
+			{Name: "d", Status: goTx.StepFailed},
+		},
+	}
+
+	if got, want := CompensationOrder(report), []string{"c", "b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("CompensationOrder() = %v, want %v", got, want)
+	}
+	AssertStepStatus(t, report, "d", goTx.StepFailed)
+}
+
+func TestInjector_NestedSaga(t *testing.T) {
+	log := &stepLog{}
+	child := orderSaga(t, log)
+	def, err := goTx.NewSagaBuilder("checkout").
+		AppendSaga("order", child).
+		AppendStep(goTx.Step{Name: "notify", Update: func(context.Context) error { log.add("notify"); return nil }}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := NewInjector(1).FailStep("ship").Definition(def).NewExecution()
+	if err := exec.Execute(context.Background()); !errors.Is(err, ErrInjected) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrInjected)
+	}
+
+	report := exec.Report()
+	AssertState(t, report, goTx.ExecutionCompensated)
+	AssertStepStatus(t, report, "order", goTx.StepFailed)
+	AssertCompensated(t, report.Steps[0].Child, "charge", "reserve")
+	if got, want := log.String(), "[reserve charge undo charge undo reserve]"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
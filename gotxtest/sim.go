@@ -0,0 +1,275 @@
+package gotxtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Simulation is a goTx.Scheduler that runs async sagas and chains under a
+// deterministic interleaving chosen by its seed, with a fake clock for retries.
+//
+// Only one task runs at a time, and tasks switch only at scheduling points:
+// when a task starts others with Go, waits for them, sleeps or calls Yield.
+// Among the tasks that can run, the next one is picked at random from the seed,
+// and the fake clock jumps forward when every task sleeps. Running the same
+// code under a simulation with the same seed therefore replays the same
+// schedule. Steps must not block on anything but the simulation, such as a
+// channel written by another task, or the simulation hangs.
+type Simulation struct {
+	seed  int64
+	rnd   *rand.Rand
+	start time.Time
+	now   time.Time
+
+	tasks    []*simTask
+	current  *simTask
+	nextID   int
+	yield    chan struct{}
+	trace    []string
+	panicked string
+}
+
+type taskState int
+
+const (
+	taskRunnable taskState = iota
+	taskRunning
+	taskSleeping
+	taskWaiting
+	taskDone
+)
+
+type simTask struct {
+	id     int
+	state  taskState
+	resume chan struct{}
+	wake   time.Time
+	group  *simGroup
+	// waitFor is the group the task waits for.
+	waitFor *simGroup
+}
+
+type simGroup struct {
+	pending int
+}
+
+func NewSimulation(seed int64) *Simulation {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &Simulation{
+		seed:  seed,
+		rnd:   rand.New(rand.NewSource(seed)),
+		start: start,
+		now:   start,
+		yield: make(chan struct{}),
+	}
+}
+
+func (s *Simulation) Seed() int64 {
+	return s.seed
+}
+
+// Run runs fn as the first task of the simulation and returns once it and
+// every task it started returned. It panics if a task panics or if every
+// remaining task waits for another one.
+func (s *Simulation) Run(fn func()) {
+	s.spawn(fn, nil)
+
+	for {
+		next := s.pick()
+		if next == nil {
+			break
+		}
+		next.state = taskRunning
+		s.current = next
+		s.record(next, "runs")
+		next.resume <- struct{}{}
+		<-s.yield
+		s.current = nil
+
+		if s.panicked != "" {
+			panic(s.panicked)
+		}
+	}
+
+	if len(s.tasks) > 0 {
+		panic(fmt.Sprintf("gotxtest: simulation with seed %d deadlocked with %d waiting tasks", s.seed, len(s.tasks)))
+	}
+}
+
+// Go starts the functions as new tasks and lets the scheduler pick the task
+// that runs next.
+func (s *Simulation) Go(fns ...func()) func() {
+	cur := s.running("Go")
+	group := &simGroup{pending: len(fns)}
+	for _, fn := range fns {
+		t := s.spawn(fn, group)
+		s.record(cur, fmt.Sprintf("starts task %d", t.id))
+	}
+	s.Yield()
+
+	return func() { s.wait(group) }
+}
+
+// Sleep blocks the calling task for d of simulated time. Cancellation of ctx is
+// only noticed when the task wakes up.
+func (s *Simulation) Sleep(ctx context.Context, d time.Duration) error {
+	cur := s.running("Sleep")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cur.state = taskSleeping
+	cur.wake = s.now.Add(d)
+	s.record(cur, fmt.Sprintf("sleeps %v", d))
+	s.block(cur)
+	return ctx.Err()
+}
+
+// Now returns the simulated time, which starts at midnight of January 1st 2000
+// UTC.
+func (s *Simulation) Now() time.Time {
+	return s.now
+}
+
+// Float64 returns a number in [0, 1) from the seed. The retries of goTx use it
+// for the jitter of exponential backoffs.
+func (s *Simulation) Float64() float64 {
+	return s.rnd.Float64()
+}
+
+// Yield is a scheduling point: the calling task may be suspended so that
+// another one runs. Steps call it between operations whose interleaving with
+// other steps matters.
+func (s *Simulation) Yield() {
+	cur := s.running("Yield")
+	cur.state = taskRunnable
+	s.block(cur)
+}
+
+// Trace returns the schedule of the simulation so far: which task ran, started
+// other tasks, slept or waited, and when.
+func (s *Simulation) Trace() []string {
+	return append([]string(nil), s.trace...)
+}
+
+func (s *Simulation) spawn(fn func(), group *simGroup) *simTask {
+	s.nextID++
+	t := &simTask{id: s.nextID, resume: make(chan struct{}), group: group}
+	s.tasks = append(s.tasks, t)
+
+	go func() {
+		<-t.resume
+		defer func() {
+			if r := recover(); r != nil {
+				s.panicked = fmt.Sprintf("gotxtest: task %d of simulation with seed %d panicked: %v", t.id, s.seed, r)
+			}
+			s.finish(t)
+			s.yield <- struct{}{}
+		}()
+		fn()
+	}()
+	return t
+}
+
+func (s *Simulation) finish(t *simTask) {
+	t.state = taskDone
+	s.record(t, "returns")
+	if t.group != nil {
+		t.group.pending--
+	}
+
+	for i, task := range s.tasks {
+		if task == t {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Simulation) wait(group *simGroup) {
+	cur := s.running("wait")
+	if group.pending == 0 {
+		return
+	}
+
+	cur.state = taskWaiting
+	cur.waitFor = group
+	s.record(cur, "waits")
+	s.block(cur)
+	cur.waitFor = nil
+}
+
+func (s *Simulation) block(t *simTask) {
+	s.yield <- struct{}{}
+	<-t.resume
+}
+
+// pick returns the next task to run, advancing the clock if every task
+// sleeps, or nil if no task can run.
+func (s *Simulation) pick() *simTask {
+	ready := s.ready()
+	if len(ready) == 0 {
+		var wake time.Time
+		for _, t := range s.tasks {
+			if t.state == taskSleeping && (wake.IsZero() || t.wake.Before(wake)) {
+				wake = t.wake
+			}
+		}
+		if wake.IsZero() {
+			return nil
+		}
+		s.now = wake
+		ready = s.ready()
+	}
+	return ready[s.rnd.Intn(len(ready))]
+}
+
+func (s *Simulation) ready() []*simTask {
+	var ready []*simTask
+	for _, t := range s.tasks {
+		switch {
+		case t.state == taskRunnable,
+			t.state == taskSleeping && !t.wake.After(s.now),
+			t.state == taskWaiting && t.waitFor.pending == 0:
+			ready = append(ready, t)
+		}
+	}
+	return ready
+}
+
+func (s *Simulation) running(method string) *simTask {
+	if s.current == nil {
+		panic(fmt.Sprintf("gotxtest: Simulation.%s called outside of a simulated task", method))
+	}
+	return s.current
+}
+
+func (s *Simulation) record(t *simTask, event string) {
+	s.trace = append(s.trace, fmt.Sprintf("%v task %d %s", s.now.Sub(s.start), t.id, event))
+}
+
+// Simulate runs fn once for each of the seeds 0 to seeds-1, each in a subtest
+// named after its seed, with a new simulation of that seed. A failing schedule
+// is replayed by running its subtest alone, e.g. -run 'TestName/seed=17$'; its
+// trace is logged when it fails.
+func Simulate(t *testing.T, seeds int, fn func(t *testing.T, sim *Simulation)) {
+	t.Helper()
+
+	for seed := int64(0); seed < int64(seeds); seed++ {
+		t.Run(
+			fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+				sim := NewSimulation(seed)
+				defer func() {
+					if t.Failed() {
+						t.Logf("schedule:\n%s", strings.Join(sim.Trace(), "\n"))
+					}
+				}()
+				fn(t, sim)
+			},
+		)
+	}
+}
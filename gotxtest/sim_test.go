@@ -0,0 +1,142 @@
+package gotxtest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/interwubs/goTx"
+)
+
+// racyDeposits runs three async deposits that read the balance, yield and
+// write it back, so that some schedules lose updates.
+func racyDeposits(sim *Simulation) (balance int, trace []string) {
+	deposit := func() error {
+		b := balance
+		sim.Yield()
+		balance = b + 10
+		return nil
+	}
+
+	tx := goTx.NewSagaTx(true)
+	tx.SetScheduler(sim)
+	for range 3 {
+		tx.Append(deposit, func() error { return nil })
+	}
+	sim.Run(func() { tx.ExecuteAll() })
+	return balance, sim.Trace()
+}
+
+func TestSimulation_SagaTx(t *testing.T) {
+	outcomes := make(map[int]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		balance, trace := racyDeposits(NewSimulation(seed))
+		replayed, replayedTrace := racyDeposits(NewSimulation(seed))
+		if balance != replayed || !slices.Equal(trace, replayedTrace) {
+			t.Fatalf("seed %d: balance %d with schedule %q, replayed %d with schedule %q", seed, balance, trace, replayed, replayedTrace)
+		}
+		outcomes[balance] = true
+	}
+
+	if !outcomes[30] || len(outcomes) < 2 {
+		t.Errorf("balances over 20 seeds = %v, want 30 and lost updates", outcomes)
+	}
+}
+
+func TestSimulation_ChainRetries(t *testing.T) {
+	run := func(seed int64) (time.Duration, []goTx.OperationResult, error) {
+		sim := NewSimulation(seed)
+		failures := map[string]int{"reserve": 2, "charge": 1}
+		op := func(name string) *goTx.ChainOperation {
+			return goTx.NewFallbackOperation(name, func(context.Context) error {
+				if failures[name] > 0 {
+					failures[name]--
+					return errors.New("unavailable")
+				}
+				return nil
+			})
+		}
+
+		chain := goTx.NewChain(true)
+		chain.SetScheduler(sim)
+		chain.RetryOptions = goTx.RetryOptions{
+			MaxRetries: 3,
+			Backoff: &goTx.ExponentialBackoff{
+				InitialInterval: time.Hour,
+				MaxInterval:     10 * time.Hour,
+				Multiplier:      2,
+				RandomFactor:    0.5,
+			},
+		}
+		chain.SetRetries(true)
+		chain.Append(op("reserve"))
+		chain.Append(op("charge"))
+
+		var results []goTx.OperationResult
+		var err error
+		sim.Run(func() { results, err = chain.Execute(context.Background()) })
+		return sim.Now().Sub(NewSimulation(seed).Now()), results, err
+	}
+
+	start := time.Now()
+	elapsed, results, err := run(1)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, result := range results {
+		if !result.Succeeded() {
+			t.Errorf("operation %q failed: %v", result.Name, result.Errors)
+		}
+	}
+	// reserve waits one hour, then two hours with up to 50% jitter.
+	if elapsed < 2*time.Hour || elapsed > 4*time.Hour {
+		t.Errorf("simulated time = %v, want between 2h and 4h", elapsed)
+	}
+	if real := time.Since(start); real > time.Second {
+		t.Errorf("simulation took %v of real time", real)
+	}
+
+	if replayed, _, _ := run(1); replayed != elapsed {
+		t.Errorf("replayed simulated time = %v, want %v", replayed, elapsed)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	var seeds []int64
+	Simulate(t, 5, func(t *testing.T, sim *Simulation) {
+		seeds = append(seeds, sim.Seed())
+
+		balance := 0
+		tx := goTx.NewSagaTx(true)
+		tx.SetScheduler(sim)
+		for range 3 {
+			tx.Append(func() error { balance += 10; sim.Yield(); return nil }, func() error { return nil })
+		}
+		sim.Run(func() { tx.ExecuteAll() })
+
+		if balance != 30 {
+			t.Errorf("balance = %d, want 30", balance)
+		}
+	})
+
+	if !slices.Equal(seeds, []int64{0, 1, 2, 3, 4}) {
+		t.Errorf("seeds = %v, want 0 to 4", seeds)
+	}
+}
+
+func TestSimulation_Deadlock(t *testing.T) {
+	sim := NewSimulation(1)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Run() did not panic, want deadlock")
+		}
+	}()
+	sim.Run(func() {
+		wait := sim.Go(func() {})
+		// A task waiting for itself never wakes up.
+		sim.wait(&simGroup{pending: 1})
+		wait()
+	})
+}
@@ -0,0 +1,505 @@
+package goTx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Graph is a renderable view of a saga or chain: its steps, their
+// compensations, fallbacks and parallel groups. It can be rendered as Graphviz
+// DOT or Mermaid, optionally coloured with the outcome of an execution.
+type Graph struct {
+	name     string
+	nodes    []*graphNode
+	edges    []graphEdge
+	clusters []graphCluster
+	nextID   int
+}
+
+type graphNodeKind int
+
+const (
+	nodeTerminal graphNodeKind = iota
+	nodeStep
+	nodeCompensation
+	nodeFallback
+)
+
+type graphNode struct {
+	id     string
+	label  string
+	kind   graphNodeKind
+	status string
+	// path identifies the node for report overlays: the step names from the
+	// root saga down to the step, or the operation index for chains.
+	path string
+}
+
+type graphEdge struct {
+	from, to string
+	label    string
+	dashed   bool
+}
+
+type graphCluster struct {
+	label string
+	nodes []string
+	// children are the indexes of the clusters drawn inside this one, and
+	// nested is set on the clusters drawn inside another one.
+	children []int
+	nested   bool
+}
+
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusUndone  = "undone"
+	statusBroken  = "broken"
+	statusRunning = "running"
+)
+
+// NewSagaGraph returns the graph of a saga definition. Nested sagas are drawn
+// as clusters inside the cluster of their parent.
+func NewSagaGraph(def *SagaDefinition) *Graph {
+	g := &Graph{name: def.name}
+	start := g.addNode("start", nodeTerminal, "")
+	end := g.addNode("end", nodeTerminal, "")
+	first, last, _, _ := g.addSagaSteps(def, "", -1)
+	if first == "" {
+		g.addEdge(start, end, "", false)
+		return g
+	}
+	g.addEdge(start, first, "", false)
+	g.addEdge(last, end, "", false)
+	return g
+}
+
+// addSagaSteps adds the steps of def in sequence, inside cluster unless it is
+// -1, and returns the first and last step node and the first and last
+// compensation node of the chain of compensations, which runs in reverse
+// order.
+func (g *Graph) addSagaSteps(def *SagaDefinition, prefix string, cluster int) (first, last, firstComp, lastComp string) {
+	var prevStep, prevComp string
+	for _, step := range def.steps {
+		path := prefix + step.Name
+		var stepFirst, stepLast, compFirst, compLast string
+		if step.child != nil {
+			child := g.addCluster(step.Name+" ("+step.child.name+")", cluster)
+			stepFirst, stepLast, compFirst, compLast = g.addSagaSteps(step.child, path+"/", child)
+			if stepFirst == "" {
+				// A nested saga without steps is drawn as an empty cluster
+				// that the steps around it skip.
+				continue
+			}
+		} else {
+			stepFirst = g.addNode(step.Name, nodeStep, path)
+			stepLast = stepFirst
+			g.addToCluster(cluster, stepFirst)
+			if step.Compensate != nil {
+				compFirst = g.addNode("compensate "+step.Name, nodeCompensation, path)
+				compLast = compFirst
+				g.addToCluster(cluster, compFirst)
+				// A failed step is not compensated; its compensation undoes it
+				// when a later step fails.
+				g.addEdge(stepFirst, compFirst, "on later failure", true)
+			}
+		}
+
+		if prevStep != "" {
+			g.addEdge(prevStep, stepFirst, "", false)
+		} else {
+			first = stepFirst
+		}
+		prevStep = stepLast
+
+		if compFirst != "" {
+			if prevComp != "" {
+				g.addEdge(compLast, prevComp, "", true)
+			} else {
+				lastComp = compLast
+			}
+			prevComp = compFirst
+		}
+	}
+	return first, prevStep, prevComp, lastComp
+}
+
+// NewSagaTxGraph returns the graph of a SagaTx. Its steps are unnamed and drawn
+// as "step 1", "step 2" and so on; the steps of an async SagaTx are drawn as a
+// parallel group.
+//
+// Unlike a saga execution, a SagaTx also compensates the step that failed, so
+// the edge to the compensation of a step is labelled "on failure".
+func NewSagaTxGraph(tx *SagaTx) *Graph {
+	g := &Graph{name: "saga"}
+	start := g.addNode("start", nodeTerminal, "")
+	end := g.addNode("end", nodeTerminal, "")
+
+	prev := start
+	var prevComp string
+	var parallel []string
+	for i := range tx.txFuncs {
+		name := fmt.Sprintf("step %d", i+1)
+		step := g.addNode(name, nodeStep, name)
+		comp := g.addNode("compensate "+name, nodeCompensation, name)
+		g.addEdge(step, comp, "on failure", true)
+		if prevComp != "" {
+			g.addEdge(comp, prevComp, "", true)
+		}
+		prevComp = comp
+
+		if tx.async {
+			g.addEdge(start, step, "", false)
+			g.addEdge(step, end, "", false)
+			parallel = append(parallel, step)
+			continue
+		}
+		g.addEdge(prev, step, "", false)
+		prev = step
+	}
+
+	if tx.async {
+		if len(parallel) > 0 {
+			g.clusters = append(g.clusters, graphCluster{label: "parallel", nodes: parallel})
+		} else {
+			g.addEdge(start, end, "", false)
+		}
+	} else {
+		g.addEdge(prev, end, "", false)
+	}
+	return g
+}
+
+// NewChainGraph returns the graph of a chain. The alternates of an operation
+// are linked by fallback edges, or hedge edges for hedged operations, and the
+// operations of an async chain are drawn as a parallel group.
+func NewChainGraph(chain *Chain) *Graph {
+	g := &Graph{name: "chain"}
+	start := g.addNode("start", nodeTerminal, "")
+	end := g.addNode("end", nodeTerminal, "")
+
+	prev := start
+	parallel := -1
+	if chain.async && len(chain.ops) > 0 {
+		parallel = g.addCluster("parallel", -1)
+	}
+	var prevCleanup string
+	for i, op := range chain.ops {
+		name := op.name
+		if name == "" {
+			name = fmt.Sprintf("operation %d", i+1)
+		}
+		path := fmt.Sprint(i)
+
+		var ids []string
+		primary := g.addNode(name, nodeStep, path)
+		ids = append(ids, primary)
+		last := primary
+		for j := 1; j < len(op.alternates); j++ {
+			alt := g.addNode(fmt.Sprintf("%s alternate %d", name, j), nodeFallback, fmt.Sprintf("%s.%d", path, j))
+			if op.hedgeDelay > 0 {
+				g.addEdge(last, alt, "hedge after "+op.hedgeDelay.String(), true)
+			} else {
+				g.addEdge(last, alt, "on failure", true)
+			}
+			ids = append(ids, alt)
+			last = alt
+		}
+		if len(ids) > 1 {
+			alternates := g.addCluster(name, parallel)
+			g.clusters[alternates].nodes = ids
+		} else {
+			g.addToCluster(parallel, primary)
+		}
+
+		if op.cleanup != nil {
+			cleanup := g.addNode("cleanup "+name, nodeCompensation, path)
+			g.addEdge(primary, cleanup, "on later failure", true)
+			if prevCleanup != "" {
+				g.addEdge(cleanup, prevCleanup, "", true)
+			}
+			prevCleanup = cleanup
+		}
+
+		if chain.async {
+			g.addEdge(start, primary, "", false)
+			g.addEdge(primary, end, "", false)
+			continue
+		}
+		g.addEdge(prev, primary, "", false)
+		prev = primary
+	}
+
+	if chain.async {
+		if parallel < 0 {
+			g.addEdge(start, end, "", false)
+		}
+	} else {
+		g.addEdge(prev, end, "", false)
+	}
+	return g
+}
+
+// WithReport colours the steps and compensations of a saga graph with their
+// status in report.
+func (g *Graph) WithReport(report *ExecutionReport) *Graph {
+	statuses := make(map[string]StepStatus)
+	var collect func(r *ExecutionReport, prefix string)
+	collect = func(r *ExecutionReport, prefix string) {
+		for _, step := range r.Steps {
+			statuses[prefix+step.Name] = step.Status
+			if step.Child != nil {
+				collect(step.Child, prefix+step.Name+"/")
+			}
+		}
+	}
+	collect(report, "")
+
+	for _, n := range g.nodes {
+		status, ok := statuses[n.path]
+		if !ok {
+			continue
+		}
+		switch n.kind {
+		case nodeStep:
+			switch status {
+			case StepRunning:
+				n.status = statusRunning
+			case StepSucceeded, StepCompensating, StepCompensated, StepCompensationFailed:
+				n.status = statusOK
+			case StepFailed:
+				n.status = statusFailed
+			}
+		case nodeCompensation:
+			switch status {
+			case StepCompensating:
+				n.status = statusRunning
+			case StepCompensated:
+				n.status = statusUndone
+			case StepCompensationFailed:
+				n.status = statusBroken
+			}
+		}
+	}
+	return g
+}
+
+// WithChainResults colours the operations, alternates and cleanups of a chain
+// graph with the results of an execution of the chain.
+func (g *Graph) WithChainResults(results []OperationResult) *Graph {
+	for _, n := range g.nodes {
+		var op, alt int
+		if _, err := fmt.Sscanf(n.path, "%d.%d", &op, &alt); err != nil {
+			if _, err := fmt.Sscanf(n.path, "%d", &op); err != nil {
+				continue
+			}
+			alt = 0
+		}
+		if op >= len(results) {
+			continue
+		}
+
+		result := results[op]
+		switch n.kind {
+		case nodeStep, nodeFallback:
+			if result.Alternate == alt {
+				n.status = statusOK
+			} else if slices.Contains(result.FailedAlternates, alt) {
+				n.status = statusFailed
+			}
+		case nodeCompensation:
+			if result.CleanupErr != nil {
+				n.status = statusBroken
+			} else if result.CleanedUp {
+				n.status = statusUndone
+			}
+		}
+	}
+	return g
+}
+
+func (g *Graph) addNode(label string, kind graphNodeKind, path string) string {
+	g.nextID++
+	n := &graphNode{id: fmt.Sprintf("n%d", g.nextID), label: label, kind: kind, path: path}
+	g.nodes = append(g.nodes, n)
+	return n.id
+}
+
+// addCluster adds an empty cluster inside parent, unless it is -1, and returns
+// its index.
+func (g *Graph) addCluster(label string, parent int) int {
+	i := len(g.clusters)
+	g.clusters = append(g.clusters, graphCluster{label: label, nested: parent >= 0})
+	if parent >= 0 {
+		g.clusters[parent].children = append(g.clusters[parent].children, i)
+	}
+	return i
+}
+
+func (g *Graph) addToCluster(cluster int, id string) {
+	if cluster >= 0 {
+		g.clusters[cluster].nodes = append(g.clusters[cluster].nodes, id)
+	}
+}
+
+func (g *Graph) addEdge(from, to, label string, dashed bool) {
+	g.edges = append(g.edges, graphEdge{from: from, to: to, label: label, dashed: dashed})
+}
+
+var dotColors = map[string]string{
+	statusOK:      "palegreen",
+	statusFailed:  "lightcoral",
+	statusUndone:  "orange",
+	statusBroken:  "red",
+	statusRunning: "lightblue",
+}
+
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.name))
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+
+	for _, n := range g.nodes {
+		attrs := []string{"label=" + dotQuote(n.label)}
+		switch n.kind {
+		case nodeTerminal:
+			attrs = append(attrs, "shape=circle")
+		case nodeCompensation:
+			attrs = append(attrs, "shape=note")
+		case nodeFallback:
+			attrs = append(attrs, "shape=box")
+		}
+		if color, ok := dotColors[n.status]; ok {
+			attrs = append(attrs, `style="rounded,filled"`, "fillcolor="+color)
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", n.id, strings.Join(attrs, ", "))
+	}
+
+	var cluster func(i int, indent string)
+	cluster = func(i int, indent string) {
+		c := g.clusters[i]
+		fmt.Fprintf(&b, "%ssubgraph cluster_%d {\n", indent, i)
+		fmt.Fprintf(&b, "%s\tlabel=%s;\n", indent, dotQuote(c.label))
+		for _, id := range c.nodes {
+			fmt.Fprintf(&b, "%s\t%s;\n", indent, id)
+		}
+		for _, child := range c.children {
+			cluster(child, indent+"\t")
+		}
+		b.WriteString(indent + "}\n")
+	}
+	for i, c := range g.clusters {
+		if !c.nested {
+			cluster(i, "\t")
+		}
+	}
+
+	for _, e := range g.edges {
+		var attrs []string
+		if e.label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.label))
+		}
+		if e.dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "\t%s -> %s [%s];\n", e.from, e.to, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", e.from, e.to)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var mermaidStyles = map[string]string{
+	statusOK:      "fill:#98fb98",
+	statusFailed:  "fill:#f08080",
+	statusUndone:  "fill:#ffa500",
+	statusBroken:  "fill:#ff0000",
+	statusRunning: "fill:#add8e6",
+}
+
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	inCluster := make(map[string]bool)
+	var cluster func(i int, indent string)
+	cluster = func(i int, indent string) {
+		c := g.clusters[i]
+		fmt.Fprintf(&b, "%ssubgraph c%d[%s]\n", indent, i, mermaidQuote(c.label))
+		for _, id := range c.nodes {
+			inCluster[id] = true
+			b.WriteString(indent + "\t" + g.mermaidNode(id) + "\n")
+		}
+		for _, child := range c.children {
+			cluster(child, indent+"\t")
+		}
+		b.WriteString(indent + "end\n")
+	}
+	for i, c := range g.clusters {
+		if !c.nested {
+			cluster(i, "\t")
+		}
+	}
+	for _, n := range g.nodes {
+		if !inCluster[n.id] {
+			b.WriteString("\t" + g.mermaidNode(n.id) + "\n")
+		}
+	}
+
+	for _, e := range g.edges {
+		arrow := "-->"
+		if e.dashed {
+			arrow = "-.->"
+		}
+		if e.label != "" {
+			fmt.Fprintf(&b, "\t%s %s|%s| %s\n", e.from, arrow, mermaidQuote(e.label), e.to)
+		} else {
+			fmt.Fprintf(&b, "\t%s %s %s\n", e.from, arrow, e.to)
+		}
+	}
+
+	used := make(map[string][]string)
+	for _, n := range g.nodes {
+		if n.status != "" {
+			used[n.status] = append(used[n.status], n.id)
+		}
+	}
+	for _, status := range []string{statusOK, statusFailed, statusUndone, statusBroken, statusRunning} {
+		if ids, ok := used[status]; ok {
+			fmt.Fprintf(&b, "\tclassDef %s %s\n", status, mermaidStyles[status])
+			fmt.Fprintf(&b, "\tclass %s %s\n", strings.Join(ids, ","), status)
+		}
+	}
+	return b.String()
+}
+
+func (g *Graph) mermaidNode(id string) string {
+	for _, n := range g.nodes {
+		if n.id != id {
+			continue
+		}
+		switch n.kind {
+		case nodeTerminal:
+			return fmt.Sprintf("%s((%s))", n.id, mermaidQuote(n.label))
+		case nodeCompensation:
+			return fmt.Sprintf("%s[/%s/]", n.id, mermaidQuote(n.label))
+		default:
+			return fmt.Sprintf("%s[%s]", n.id, mermaidQuote(n.label))
+		}
+	}
+	return id
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s) + `"`
+}
@@ -0,0 +1,302 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGraph(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+
+	child, err := NewSagaBuilder("shipping").
+		AppendStep(Step{Name: "reserve", Update: noop, Compensate: noop}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, err := NewSagaBuilder("order").
+		AppendStep(Step{Name: "charge", Update: noop, Compensate: noop}).
+		AppendSaga("ship", child).
+		AppendStep(Step{Name: "notify", Update: noop}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	async := NewSagaTx(true)
+	async.Append(func() error { return nil }, func() error { return nil })
+	async.Append(func() error { return nil }, func() error { return nil })
+
+	chain := NewChain(false)
+	chain.Append(NewFallbackOperation("read", noop, noop).WithHedging(50 * time.Millisecond))
+	chain.Append(NewFallbackOperation("write", noop).WithCleanup(noop))
+
+	tests := []struct {
+		name        string
+		graph       *Graph
+		wantDOT     []string
+		wantMermaid []string
+	}{
+		{
+			name:  "saga definition",
+			graph: NewSagaGraph(def),
+			wantDOT: []string{
+				`digraph "order" {`,
+				`n3 [label="charge"];`,
+				`n4 [label="compensate charge", shape=note];`,
+				`n3 -> n4 [label="on later failure", style=dashed];`,
+				`label="ship (shipping)";`,
+				`n3 -> n5;`,
+				`n6 -> n4 [style=dashed];`,
+				`n7 -> n2;`,
+			},
+			wantMermaid: []string{
+				"flowchart LR",
+				`subgraph c0["ship (shipping)"]`,
+				`n4[/"compensate charge"/]`,
+				`n3 -.->|"on later failure"| n4`,
+				"n5 --> n7",
+			},
+		},
+		{
+			name:  "async saga",
+			graph: NewSagaTxGraph(async),
+			wantDOT: []string{
+				`label="parallel";`,
+				`n3 -> n4 [label="on failure", style=dashed];`,
+				"n1 -> n3;",
+				"n1 -> n5;",
+				"n5 -> n2;",
+				"n6 -> n4 [style=dashed];",
+			},
+			wantMermaid: []string{
+				`subgraph c0["parallel"]`,
+				"n1 --> n3",
+				"n1 --> n5",
+			},
+		},
+		{
+			name:  "chain",
+			graph: NewChainGraph(chain),
+			wantDOT: []string{
+				`n3 -> n4 [label="hedge after 50ms", style=dashed];`,
+				`label="read";`,
+				`n5 [label="write"];`,
+				`n5 -> n6 [label="on later failure", style=dashed];`,
+				"n3 -> n5;",
+			},
+			wantMermaid: []string{
+				`n3 -.->|"hedge after 50ms"| n4`,
+				`n6[/"cleanup write"/]`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				dot := tt.graph.DOT()
+				for _, want := range tt.wantDOT {
+					if !strings.Contains(dot, want) {
+						t.Errorf("DOT() missing %q in:\n%s", want, dot)
+					}
+				}
+				mermaid := tt.graph.Mermaid()
+				for _, want := range tt.wantMermaid {
+					if !strings.Contains(mermaid, want) {
+						t.Errorf("Mermaid() missing %q in:\n%s", want, mermaid)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestGraph_NestedSagas(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+	saga := func(name string, steps ...Step) *SagaDefinition {
+		b := NewSagaBuilder(name)
+		for _, step := range steps {
+			b.AppendStep(step)
+		}
+		def, err := b.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return def
+	}
+	in := saga("in", Step{Name: "pack", Update: noop, Compensate: noop})
+	mid := saga("mid", Step{Name: "label", Update: noop}, Step{Name: "in", child: in})
+	def := saga("out", Step{Name: "charge", Update: noop}, Step{Name: "mid", child: mid})
+
+	empty := saga("empty", Step{Name: "p1", Update: noop}, Step{Name: "none", child: saga("none")}, Step{Name: "p2", Update: noop})
+
+	chain := NewChain(true)
+	chain.Append(NewFallbackOperation("read", noop, noop))
+	chain.Append(NewFallbackOperation("write", noop))
+
+	tests := []struct {
+		name        string
+		graph       *Graph
+		wantDOT     string
+		wantMermaid string
+	}{
+		{
+			name:  "nested saga without steps",
+			graph: NewSagaGraph(empty),
+			wantDOT: "\tn3 -> n4;\n" +
+				"\tn1 -> n3;\n" +
+				"\tn4 -> n2;\n",
+			wantMermaid: "\tsubgraph c0[\"none (none)\"]\n" +
+				"\tend\n",
+		},
+		{
+			name:  "nested sagas",
+			graph: NewSagaGraph(def),
+			wantDOT: "\tsubgraph cluster_0 {\n" +
+				"\t\tlabel=\"mid (mid)\";\n" +
+				"\t\tn4;\n" +
+				"\t\tsubgraph cluster_1 {\n" +
+				"\t\t\tlabel=\"in (in)\";\n" +
+				"\t\t\tn5;\n" +
+				"\t\t\tn6;\n" +
+				"\t\t}\n" +
+				"\t}\n",
+			wantMermaid: "\tsubgraph c0[\"mid (mid)\"]\n" +
+				"\t\tn4[\"label\"]\n" +
+				"\t\tsubgraph c1[\"in (in)\"]\n" +
+				"\t\t\tn5[\"pack\"]\n" +
+				"\t\t\tn6[/\"compensate pack\"/]\n" +
+				"\t\tend\n" +
+				"\tend\n",
+		},
+		{
+			name:  "async chain with alternates",
+			graph: NewChainGraph(chain),
+			wantDOT: "\tsubgraph cluster_0 {\n" +
+				"\t\tlabel=\"parallel\";\n" +
+				"\t\tn5;\n" +
+				"\t\tsubgraph cluster_1 {\n" +
+				"\t\t\tlabel=\"read\";\n" +
+				"\t\t\tn3;\n" +
+				"\t\t\tn4;\n" +
+				"\t\t}\n" +
+				"\t}\n",
+			wantMermaid: "\tsubgraph c0[\"parallel\"]\n" +
+				"\t\tn5[\"write\"]\n" +
+				"\t\tsubgraph c1[\"read\"]\n" +
+				"\t\t\tn3[\"read\"]\n" +
+				"\t\t\tn4[\"read alternate 1\"]\n" +
+				"\t\tend\n" +
+				"\tend\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if dot := tt.graph.DOT(); !strings.Contains(dot, tt.wantDOT) {
+					t.Errorf("DOT() missing\n%s\nin:\n%s", tt.wantDOT, dot)
+				}
+				if mermaid := tt.graph.Mermaid(); !strings.Contains(mermaid, tt.wantMermaid) {
+					t.Errorf("Mermaid() missing\n%s\nin:\n%s", tt.wantMermaid, mermaid)
+				}
+			},
+		)
+	}
+}
+
+func TestGraph_WithReport(t *testing.T) {
+	log := &stepLog{}
+	def, err := NewSagaBuilder("transfer").
+		AppendStep(loggedStep(log, "debit", nil)).
+		AppendStep(loggedStep(log, "credit", errors.New("account closed"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, _ := def.Execute(context.Background())
+
+	dot := NewSagaGraph(def).WithReport(exec.Report()).DOT()
+	for _, want := range []string{
+		`n3 [label="debit", style="rounded,filled", fillcolor=palegreen];`,
+		`n4 [label="compensate debit", shape=note, style="rounded,filled", fillcolor=orange];`,
+		`n5 [label="credit", style="rounded,filled", fillcolor=lightcoral];`,
+		`n6 [label="compensate credit", shape=note];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT() missing %q in:\n%s", want, dot)
+		}
+	}
+
+	mermaid := NewSagaGraph(def).WithReport(exec.Report()).Mermaid()
+	for _, want := range []string{
+		"class n3 ok",
+		"class n5 failed",
+		"class n4 undone",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid() missing %q in:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestGraph_WithChainResults(t *testing.T) {
+	failing := func(context.Context) error { return errors.New("unavailable") }
+	noop := func(context.Context) error { return nil }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name       string
+		op         *ChainOperation
+		wantFailed []int
+		wantDOT    []string
+	}{
+		{
+			name:       "fallback",
+			op:         NewFallbackOperation("read", failing, noop),
+			wantFailed: []int{0},
+			wantDOT: []string{
+				`n3 [label="read", style="rounded,filled", fillcolor=lightcoral];`,
+				`n4 [label="read alternate 1", shape=box, style="rounded,filled", fillcolor=palegreen];`,
+			},
+		},
+		{
+			name:       "hedged",
+			op:         NewFallbackOperation("read", hanging, failing, noop).WithHedging(10 * time.Millisecond),
+			wantFailed: []int{1},
+			wantDOT: []string{
+				`n3 [label="read"];`,
+				`n4 [label="read alternate 1", shape=box, style="rounded,filled", fillcolor=lightcoral];`,
+				`n5 [label="read alternate 2", shape=box, style="rounded,filled", fillcolor=palegreen];`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				chain := NewChain(false)
+				chain.Append(tt.op)
+				results, err := chain.Execute(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := results[0].FailedAlternates; !slices.Equal(got, tt.wantFailed) {
+					t.Errorf("FailedAlternates = %v, want %v", got, tt.wantFailed)
+				}
+
+				dot := NewChainGraph(chain).WithChainResults(results).DOT()
+				for _, want := range tt.wantDOT {
+					if !strings.Contains(dot, want) {
+						t.Errorf("DOT() missing %q in:\n%s", want, dot)
+					}
+				}
+			},
+		)
+	}
+}
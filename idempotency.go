@@ -0,0 +1,127 @@
+package goTx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type IdempotencyScope string
+
+const (
+	ScopeUpdate     IdempotencyScope = "update"
+	ScopeCompensate IdempotencyScope = "compensate"
+)
+
+// IdempotencyRecord is the stored outcome of a step. Err is empty if the step
+// succeeded.
+type IdempotencyRecord struct {
+	Key         string
+	Err         string
+	CompletedAt time.Time
+}
+
+func (r IdempotencyRecord) err() error {
+	if r.Err == "" {
+		return nil
+	}
+	return &ReplayedError{Key: r.Key, Message: r.Err}
+}
+
+// ReplayedError is returned in place of the original error when the failed
+// outcome of a step is replayed from an IdempotencyStore.
+type ReplayedError struct {
+	Key     string
+	Message string
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+// IdempotencyStore records the outcome of the steps of saga executions. When a
+// SagaDefinition has a store, an execution that is retried or recovered with the
+// same ID returns the stored outcome of a step instead of invoking it again.
+type IdempotencyStore interface {
+	Get(ctx context.Context, key string) (IdempotencyRecord, bool, error)
+	Put(ctx context.Context, record IdempotencyRecord) error
+}
+
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	return record, ok, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(_ context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+	return nil
+}
+
+type idempotencyKeyCtxKey struct{}
+
+// IdempotencyKey returns the key of the step execution ctx was passed to. It is
+// stable across retries and recoveries of the same execution, so steps can
+// forward it to external systems to deduplicate requests.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key, ok
+}
+
+// WithIdempotencyKey returns a context carrying key as the idempotency key of a
+// step. Executions set it themselves; it is meant for code that invokes step
+// functions on behalf of a remote saga, such as participant servers.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+func stepIdempotencyKey(executionID, step string, scope IdempotencyScope) string {
+	return executionID + "/" + step + "/" + string(scope)
+}
+
+// runIdempotent invokes fn with the idempotency key of the step in its context,
+// unless store already holds the outcome for that key. Failures are only
+// recorded when they are final, i.e. not caused by ctx being done, and only if
+// recordFailure is set.
+func runIdempotent(ctx context.Context, store IdempotencyStore, key string, recordFailure bool, fn StepFunc) (replayed bool, err error) {
+	ctx = WithIdempotencyKey(ctx, key)
+	if store == nil {
+		return false, fn(ctx)
+	}
+
+	record, ok, err := store.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, record.err()
+	}
+
+	err = fn(ctx)
+	if err != nil && (!recordFailure || ctx.Err() != nil) {
+		return false, err
+	}
+
+	record = IdempotencyRecord{Key: key, CompletedAt: time.Now()}
+	if err != nil {
+		record.Err = err.Error()
+	}
+	if perr := store.Put(ctx, record); perr != nil {
+		return false, joinErrors(err, perr)
+	}
+	return false, err
+}
@@ -0,0 +1,88 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSagaExecution_Idempotency(t *testing.T) {
+	errStep := errors.New("step error")
+	store := NewMemoryIdempotencyStore()
+	calls := make(map[string]int)
+	keys := make(map[string][]string)
+
+	step := func(name string, failures int, err error) Step {
+		return Step{
+			Name: name,
+			Update: func(ctx context.Context) error {
+				calls[name]++
+				key, _ := IdempotencyKey(ctx)
+				keys[name] = append(keys[name], key)
+				if calls[name] <= failures {
+					return err
+				}
+				return nil
+			},
+			Compensate: func(ctx context.Context) error {
+				calls["rollback "+name]++
+				key, _ := IdempotencyKey(ctx)
+				keys["rollback "+name] = append(keys["rollback "+name], key)
+				return nil
+			},
+		}
+	}
+
+	def, err := NewSagaBuilder("idempotent").
+		AppendStep(step("a", 1, errStep)).
+		AppendStep(step("b", 100, errStep)).
+		WithRetries(RetryOptions{MaxRetries: 2, Backoff: &ConstantBackoff{Interval: time.Millisecond}}).
+		WithIdempotencyStore(store).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := def.NewExecutionWithID("exec-1")
+	if err := first.Execute(context.Background()); !errors.Is(err, errStep) {
+		t.Fatalf("Execute() error = %v, want %v", err, errStep)
+	}
+	if keys["a"][0] != "exec-1/a/update" || keys["a"][1] != keys["a"][0] {
+		t.Errorf("keys of a = %v, want exec-1/a/update for every attempt", keys["a"])
+	}
+	if keys["rollback a"][0] != "exec-1/a/compensate" {
+		t.Errorf("keys of rollback a = %v, want exec-1/a/compensate", keys["rollback a"])
+	}
+
+	recovered := def.NewExecutionWithID("exec-1")
+	err = recovered.Execute(context.Background())
+	var replayedErr *ReplayedError
+	if !errors.As(err, &replayedErr) || replayedErr.Key != "exec-1/b/update" {
+		t.Errorf("recovered Execute() error = %v, want replayed error of b", err)
+	}
+
+	wantCalls := map[string]int{"a": 2, "b": 2, "rollback a": 1}
+	for name, want := range wantCalls {
+		if calls[name] != want {
+			t.Errorf("calls[%q] = %d, want %d", name, calls[name], want)
+		}
+	}
+
+	report := recovered.Report()
+	for _, step := range report.Steps {
+		if !step.Replayed || step.Attempts != 0 {
+			t.Errorf("step %q Replayed = %v, Attempts = %d, want replayed without attempts", step.Name, step.Replayed, step.Attempts)
+		}
+	}
+	if a, _ := report.Step("a"); !a.CompensationReplayed || a.Status != StepCompensated {
+		t.Errorf("step a = %+v, want replayed compensation", a)
+	}
+
+	if _, err := def.Execute(context.Background()); !errors.Is(err, errStep) {
+		t.Errorf("new execution error = %v, want %v", err, errStep)
+	}
+	if calls["a"] != 3 {
+		t.Errorf("calls[a] = %d, want 3 after a new execution", calls["a"])
+	}
+}
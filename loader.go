@@ -0,0 +1,330 @@
+package goTx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidSagaDocument = errors.New("invalid saga document")
+	ErrUnknownHandler      = errors.New("unknown handler")
+	ErrHandlerRegistered   = errors.New("handler already registered")
+)
+
+// HandlerRegistry maps the handler names used in saga documents to step
+// functions.
+type HandlerRegistry struct {
+	mu       sync.Mutex
+	handlers map[string]StepFunc
+}
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{handlers: make(map[string]StepFunc)}
+}
+
+func (r *HandlerRegistry) Register(name string, fn StepFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.handlers[name]; ok {
+		return fmt.Errorf("%w: %q", ErrHandlerRegistered, name)
+	}
+	r.handlers[name] = fn
+	return nil
+}
+
+func (r *HandlerRegistry) lookup(name string) (StepFunc, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn, ok := r.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownHandler, name)
+	}
+	return fn, nil
+}
+
+type sagaDocument struct {
+	Name              string         `yaml:"name"`
+	Retry             *retryDocument `yaml:"retry"`
+	CompensationRetry *retryDocument `yaml:"compensation_retry"`
+	Steps             []stepDocument `yaml:"steps"`
+}
+
+type stepDocument struct {
+	Name               string         `yaml:"name"`
+	Handler            string         `yaml:"handler"`
+	Compensation       string         `yaml:"compensation"`
+	CompensationPolicy string         `yaml:"compensation_policy"`
+	DependsOn          []string       `yaml:"depends_on"`
+	Retry              *retryDocument `yaml:"retry"`
+	CompensationRetry  *retryDocument `yaml:"compensation_retry"`
+	Timeout            time.Duration  `yaml:"timeout"`
+}
+
+type retryDocument struct {
+	MaxRetries      int           `yaml:"max_retries"`
+	Backoff         string        `yaml:"backoff"`
+	Interval        time.Duration `yaml:"interval"`
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+	Multiplier      float64       `yaml:"multiplier"`
+	RandomFactor    float64       `yaml:"random_factor"`
+}
+
+const (
+	// CompensationRequired stops the compensation of the saga if the
+	// compensation of the step fails. It is the default.
+	CompensationRequired = "required"
+	// CompensationBestEffort ignores the errors of the compensation of the step.
+	CompensationBestEffort = "best-effort"
+	// CompensationNone means the step has no compensation.
+	CompensationNone = "none"
+)
+
+// LoadSagaDefinition builds a saga definition from a YAML or JSON document:
+//
+//	name: order
+//	retry:
+//	  max_retries: 3
+//	  backoff: exponential        # or constant, with interval
+//	  initial_interval: 1s
+//	  max_interval: 30s
+//	  multiplier: 2
+//	compensation_retry:
+//	  max_retries: -1             # unlimited
+//	  backoff: exponential
+//	  initial_interval: 1s
+//	  max_interval: 5m
+//	  multiplier: 2
+//	steps:
+//	  - name: charge
+//	    handler: payments.charge
+//	    compensation: payments.refund
+//	    timeout: 5s
+//	  - name: reserve
+//	    handler: inventory.reserve
+//	    compensation: inventory.release
+//	    compensation_policy: best-effort   # required (default), best-effort or none
+//	    retry:
+//	      max_retries: 5
+//	      backoff: constant
+//	      interval: 200ms
+//	    compensation_retry:
+//	      max_retries: 3
+//	  - name: ship
+//	    handler: shipping.ship
+//	    depends_on: [charge, reserve]
+//
+// Steps run in an order that satisfies their dependencies, keeping the document
+// order where the dependencies leave a choice. Handlers are looked up by name in
+// handlers.
+func LoadSagaDefinition(data []byte, handlers *HandlerRegistry) (*SagaDefinition, error) {
+	var doc sagaDocument
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSagaDocument, err)
+	}
+
+	steps, err := orderSteps(doc.Steps)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := NewSagaBuilder(doc.Name)
+	if doc.Retry != nil {
+		retry, err := doc.Retry.options()
+		if err != nil {
+			return nil, fmt.Errorf("%w: saga retry: %v", ErrInvalidSagaDocument, err)
+		}
+		builder.WithRetries(retry)
+	}
+	if doc.CompensationRetry != nil {
+		retry, err := doc.CompensationRetry.options()
+		if err != nil {
+			return nil, fmt.Errorf("%w: saga compensation retry: %v", ErrInvalidSagaDocument, err)
+		}
+		builder.WithCompensationRetries(retry)
+	}
+
+	for _, sd := range steps {
+		step, err := sd.step(handlers)
+		if err != nil {
+			return nil, err
+		}
+		builder.AppendStep(step)
+	}
+
+	def, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSagaDocument, err)
+	}
+	return def, nil
+}
+
+func LoadSagaDefinitionFile(path string, handlers *HandlerRegistry) (*SagaDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSagaDefinition(data, handlers)
+}
+
+func (sd stepDocument) step(handlers *HandlerRegistry) (Step, error) {
+	step := Step{Name: sd.Name, Timeout: sd.Timeout}
+	if sd.Handler == "" {
+		return Step{}, fmt.Errorf("%w: step %q has no handler", ErrInvalidSagaDocument, sd.Name)
+	}
+	if sd.Timeout < 0 {
+		return Step{}, fmt.Errorf("%w: step %q has a negative timeout", ErrInvalidSagaDocument, sd.Name)
+	}
+
+	var err error
+	if step.Update, err = handlers.lookup(sd.Handler); err != nil {
+		return Step{}, fmt.Errorf("step %q: %w", sd.Name, err)
+	}
+
+	switch sd.CompensationPolicy {
+	case "", CompensationRequired, CompensationBestEffort:
+		if sd.Compensation == "" {
+			break
+		}
+		compensate, err := handlers.lookup(sd.Compensation)
+		if err != nil {
+			return Step{}, fmt.Errorf("step %q: %w", sd.Name, err)
+		}
+		step.Compensate = compensate
+		if sd.CompensationPolicy == CompensationBestEffort {
+			step.Compensate = func(ctx context.Context) error {
+				_ = compensate(ctx)
+				return nil
+			}
+		}
+	case CompensationNone:
+		if sd.Compensation != "" {
+			return Step{}, fmt.Errorf("%w: step %q has a compensation but compensation policy %q", ErrInvalidSagaDocument, sd.Name, CompensationNone)
+		}
+	default:
+		return Step{}, fmt.Errorf("%w: step %q has unknown compensation policy %q", ErrInvalidSagaDocument, sd.Name, sd.CompensationPolicy)
+	}
+
+	if sd.Retry != nil {
+		retry, err := sd.Retry.options()
+		if err != nil {
+			return Step{}, fmt.Errorf("%w: step %q retry: %v", ErrInvalidSagaDocument, sd.Name, err)
+		}
+		step.Retry = &retry
+	}
+	if sd.CompensationRetry != nil {
+		if step.Compensate == nil {
+			return Step{}, fmt.Errorf("%w: step %q has a compensation retry but no compensation", ErrInvalidSagaDocument, sd.Name)
+		}
+		retry, err := sd.CompensationRetry.options()
+		if err != nil {
+			return Step{}, fmt.Errorf("%w: step %q compensation retry: %v", ErrInvalidSagaDocument, sd.Name, err)
+		}
+		step.CompensateRetry = &retry
+	}
+	return step, nil
+}
+
+func (rd retryDocument) options() (RetryOptions, error) {
+	if rd.MaxRetries < 1 && rd.MaxRetries != UnlimitedRetries {
+		return RetryOptions{}, fmt.Errorf("max_retries must be at least 1, or -1 for unlimited retries, got %d", rd.MaxRetries)
+	}
+
+	options := RetryOptions{MaxRetries: rd.MaxRetries}
+	switch rd.Backoff {
+	case "", "constant":
+		if rd.Interval < 0 {
+			return RetryOptions{}, errors.New("interval must not be negative")
+		}
+		options.Backoff = &ConstantBackoff{Interval: rd.Interval}
+	case "exponential":
+		if rd.InitialInterval <= 0 || rd.MaxInterval < rd.InitialInterval {
+			return RetryOptions{}, errors.New("exponential backoff needs 0 < initial_interval <= max_interval")
+		}
+		multiplier := rd.Multiplier
+		if multiplier == 0 {
+			multiplier = 2
+		}
+		if multiplier < 1 || rd.RandomFactor < 0 || rd.RandomFactor > 1 {
+			return RetryOptions{}, errors.New("exponential backoff needs multiplier >= 1 and 0 <= random_factor <= 1")
+		}
+		options.Backoff = &ExponentialBackoff{
+			InitialInterval: rd.InitialInterval,
+			MaxInterval:     rd.MaxInterval,
+			Multiplier:      multiplier,
+			RandomFactor:    rd.RandomFactor,
+		}
+	default:
+		return RetryOptions{}, fmt.Errorf("unknown backoff %q", rd.Backoff)
+	}
+	return options, nil
+}
+
+// orderSteps sorts steps topologically by their dependencies. Among the steps
+// whose dependencies are satisfied, the one declared first comes first.
+func orderSteps(steps []stepDocument) ([]stepDocument, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if _, ok := index[step.Name]; ok {
+			return nil, fmt.Errorf("%w: %w: %q", ErrInvalidSagaDocument, ErrDuplicateStepName, step.Name)
+		}
+		index[step.Name] = i
+	}
+
+	pending := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	for i, step := range steps {
+		for _, dep := range step.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: step %q depends on unknown step %q", ErrInvalidSagaDocument, step.Name, dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready []int
+	for i := range steps {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	ordered := make([]stepDocument, 0, len(steps))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, steps[i])
+
+		for _, j := range dependents[i] {
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	if len(ordered) != len(steps) {
+		var cycle []string
+		for i, step := range steps {
+			if pending[i] > 0 {
+				cycle = append(cycle, step.Name)
+			}
+		}
+		return nil, fmt.Errorf("%w: dependency cycle between steps %q", ErrInvalidSagaDocument, cycle)
+	}
+	return ordered, nil
+}
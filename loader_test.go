@@ -0,0 +1,223 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const orderSagaYAML = `
+name: order
+retry:
+  max_retries: 2
+  backoff: exponential
+  initial_interval: 1ms
+  max_interval: 5ms
+steps:
+  - name: ship
+    handler: ship
+    compensation: cancel-shipment
+    depends_on: [charge, reserve]
+  - name: reserve
+    handler: reserve
+    compensation: release
+    compensation_policy: best-effort
+    retry:
+      max_retries: 4
+      backoff: constant
+      interval: 1ms
+  - name: charge
+    handler: charge
+    compensation: refund
+    timeout: 50ms
+`
+
+const orderSagaJSON = `{
+  "name": "order",
+  "steps": [
+    {"name": "charge", "handler": "charge", "compensation": "refund"},
+    {"name": "reserve", "handler": "reserve", "compensation_policy": "none"},
+    {"name": "ship", "handler": "ship", "depends_on": ["reserve"]}
+  ]
+}`
+
+func testHandlers(log *stepLog, failures map[string]int) *HandlerRegistry {
+	handlers := NewHandlerRegistry()
+	for _, name := range []string{"charge", "refund", "reserve", "release", "ship", "cancel-shipment"} {
+		name := name
+		_ = handlers.Register(name, func(context.Context) error {
+			log.add(name)
+			if failures[name] > 0 {
+				failures[name]--
+				return errors.New(name + " failed")
+			}
+			return nil
+		})
+	}
+	return handlers
+}
+
+func TestLoadSagaDefinition(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		failures  map[string]int
+		wantSteps []string
+		wantErr   bool
+		wantLog   []string
+	}{
+		{
+			name:      "yaml-happypath",
+			doc:       orderSagaYAML,
+			wantSteps: []string{"reserve", "charge", "ship"},
+			wantLog:   []string{"reserve", "charge", "ship"},
+		},
+		{
+			name:      "yaml-step-retry",
+			doc:       orderSagaYAML,
+			failures:  map[string]int{"reserve": 3},
+			wantSteps: []string{"reserve", "charge", "ship"},
+			wantLog:   []string{"reserve", "reserve", "reserve", "reserve", "charge", "ship"},
+		},
+		{
+			name:      "yaml-best-effort-compensation",
+			doc:       orderSagaYAML,
+			failures:  map[string]int{"ship": 2, "release": 1},
+			wantSteps: []string{"reserve", "charge", "ship"},
+			wantErr:   true,
+			wantLog:   []string{"reserve", "charge", "ship", "ship", "refund", "release"},
+		},
+		{
+			name:      "json-no-compensation",
+			doc:       orderSagaJSON,
+			failures:  map[string]int{"ship": 1},
+			wantSteps: []string{"charge", "reserve", "ship"},
+			wantErr:   true,
+			wantLog:   []string{"charge", "reserve", "ship", "refund"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				log := &stepLog{}
+				failures := tt.failures
+				if failures == nil {
+					failures = make(map[string]int)
+				}
+
+				def, err := LoadSagaDefinition([]byte(tt.doc), testHandlers(log, failures))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, step := range def.Steps() {
+					names = append(names, step.Name)
+				}
+				if fmt.Sprint(names) != fmt.Sprint(tt.wantSteps) {
+					t.Errorf("steps = %v, want %v", names, tt.wantSteps)
+				}
+
+				exec, err := def.Execute(context.Background())
+				if (err != nil) != tt.wantErr {
+					t.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if log.String() != fmt.Sprint(tt.wantLog) {
+					t.Errorf("log = %v, want %v", log, tt.wantLog)
+				}
+				if tt.wantErr && exec.State() != ExecutionCompensated {
+					t.Errorf("State() = %v, want %v", exec.State(), ExecutionCompensated)
+				}
+			},
+		)
+	}
+}
+
+func TestLoadSagaDefinition_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr error
+	}{
+		{
+			name:    "syntax",
+			doc:     "name: [",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "unknown-field",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    retries: 3\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "unknown-handler",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: nope\n",
+			wantErr: ErrUnknownHandler,
+		},
+		{
+			name:    "unknown-compensation",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    compensation: nope\n",
+			wantErr: ErrUnknownHandler,
+		},
+		{
+			name:    "missing-name",
+			doc:     "steps:\n  - name: a\n    handler: charge\n",
+			wantErr: ErrEmptySagaName,
+		},
+		{
+			name:    "duplicate-step",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n  - name: a\n    handler: ship\n",
+			wantErr: ErrDuplicateStepName,
+		},
+		{
+			name:    "unknown-dependency",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    depends_on: [b]\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "cycle",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    depends_on: [b]\n  - name: b\n    handler: ship\n    depends_on: [a]\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "bad-retry",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    retry:\n      max_retries: 0\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "bad-backoff",
+			doc:     "name: order\nretry:\n  max_retries: 2\n  backoff: linear\nsteps:\n  - name: a\n    handler: charge\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "compensation-retry-without-compensation",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    compensation_retry:\n      max_retries: 3\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "bad-compensation-retry",
+			doc:     "name: order\ncompensation_retry:\n  max_retries: -2\nsteps:\n  - name: a\n    handler: charge\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "bad-compensation-policy",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    compensation_policy: sometimes\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+		{
+			name:    "bad-timeout",
+			doc:     "name: order\nsteps:\n  - name: a\n    handler: charge\n    timeout: soon\n",
+			wantErr: ErrInvalidSagaDocument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := LoadSagaDefinition([]byte(tt.doc), testHandlers(&stepLog{}, nil))
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("LoadSagaDefinition() error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,67 @@
+package goTx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MultiError is the error of a saga or chain that failed in more than one way:
+// a failed step together with failed compensations or cleanups, or several
+// failed async steps. Like an error returned by errors.Join, it matches
+// errors.Is and errors.As if any of its errors does.
+//
+// Its message lists the errors on one line, separated by semicolons; %+v
+// formats them one per line.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+func (e *MultiError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprintf(s, "%d errors:", len(e.Errors))
+		for _, err := range e.Errors {
+			fmt.Fprintf(s, "\n\t%s", strings.ReplaceAll(err.Error(), "\n", "\n\t"))
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// joinErrors returns nil if all errs are nil, the only non-nil error if there
+// is one, and a MultiError of the non-nil errors otherwise. The errors of a
+// MultiError in errs are inlined.
+func joinErrors(errs ...error) error {
+	var joined []error
+	for _, err := range errs {
+		if m, ok := err.(*MultiError); ok {
+			joined = append(joined, m.Errors...)
+		} else if err != nil {
+			joined = append(joined, err)
+		}
+	}
+
+	switch len(joined) {
+	case 0:
+		return nil
+	case 1:
+		return joined[0]
+	default:
+		return &MultiError{Errors: joined}
+	}
+}
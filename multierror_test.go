@@ -0,0 +1,166 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestJoinErrors(t *testing.T) {
+	errA := errors.New("a")
+	errB := errors.New("b")
+	errC := errors.New("c")
+
+	tests := []struct {
+		name       string
+		errs       []error
+		wantMsg    string
+		wantIs     []error
+		wantErrors int
+	}{
+		{
+			name: "no errors",
+			errs: []error{nil, nil},
+		},
+		{
+			name:    "one error",
+			errs:    []error{nil, errA},
+			wantMsg: "a",
+			wantIs:  []error{errA},
+		},
+		{
+			name:       "several errors",
+			errs:       []error{errA, nil, errB},
+			wantMsg:    "a; b",
+			wantIs:     []error{errA, errB},
+			wantErrors: 2,
+		},
+		{
+			name:       "nested multi-error",
+			errs:       []error{&MultiError{Errors: []error{errA, errB}}, errC},
+			wantMsg:    "a; b; c",
+			wantIs:     []error{errA, errB, errC},
+			wantErrors: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := joinErrors(tt.errs...)
+				if tt.wantMsg == "" {
+					if err != nil {
+						t.Fatalf("joinErrors() = %v, want nil", err)
+					}
+					return
+				}
+				if got := err.Error(); got != tt.wantMsg {
+					t.Errorf("joinErrors() = %q, want %q", got, tt.wantMsg)
+				}
+				for _, e := range tt.wantIs {
+					if !errors.Is(err, e) {
+						t.Errorf("errors.Is(%v, %v) = false", err, e)
+					}
+				}
+
+				var m *MultiError
+				if got := errors.As(err, &m); got != (tt.wantErrors > 0) {
+					t.Fatalf("errors.As(MultiError) = %t, want %t", got, tt.wantErrors > 0)
+				}
+				if m != nil && len(m.Errors) != tt.wantErrors {
+					t.Errorf("len(Errors) = %d, want %d", len(m.Errors), tt.wantErrors)
+				}
+			},
+		)
+	}
+}
+
+func TestMultiError_Format(t *testing.T) {
+	err := &MultiError{Errors: []error{errors.New(`step "b": out of stock`), errors.New("compensation failed: refund rejected")}}
+
+	if got, want := fmt.Sprintf("%v", err), `step "b": out of stock; compensation failed: refund rejected`; got != want {
+		t.Errorf("%%v = %q, want %q", got, want)
+	}
+	if got, want := fmt.Sprintf("%+v", err), "2 errors:\n\tstep \"b\": out of stock\n\tcompensation failed: refund rejected"; got != want {
+		t.Errorf("%%+v = %q, want %q", got, want)
+	}
+}
+
+func TestMultiError_Failures(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+	errRefund := errors.New("refund rejected")
+	errDeclined := errors.New("declined")
+	errCleanup := errors.New("cleanup failed")
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr []error
+	}{
+		{
+			name: "saga execution with failed compensation",
+			run: func() error {
+				log := &stepLog{}
+				a := loggedStep(log, "a", nil)
+				a.Compensate = func(context.Context) error { return errRefund }
+				def, err := NewSagaBuilder("order").
+					AppendStep(a).
+					AppendStep(loggedStep(log, "b", errOutOfStock)).
+					Build()
+				if err != nil {
+					return err
+				}
+				_, err = def.Execute(context.Background())
+				return err
+			},
+			wantErr: []error{errOutOfStock, errRefund},
+		},
+		{
+			name: "saga with failed compensation",
+			run: func() error {
+				tx := NewSagaTx(false)
+				tx.SetDeadLetterStore(NewMemoryDeadLetterStore())
+				tx.Append(func() error { return nil }, func() error { return errRefund })
+				tx.Append(func() error { return errOutOfStock }, func() error { return nil })
+				return tx.ExecuteAll()
+			},
+			wantErr: []error{errOutOfStock, errRefund},
+		},
+		{
+			name: "async chain",
+			run: func() error {
+				chain := NewChain(true)
+				chain.Append(
+					NewFallbackOperation("reserve", func(context.Context) error { return nil }).
+						WithCleanup(func(context.Context) error { return errCleanup }),
+				)
+				chain.Append(NewFallbackOperation("ship", func(context.Context) error { return errOutOfStock }))
+				chain.Append(
+					NewFallbackOperation(
+						"charge",
+						func(context.Context) error { return errDeclined },
+						func(context.Context) error { return errRefund },
+					),
+				)
+				return chain.ExecuteAll()
+			},
+			wantErr: []error{errOutOfStock, errDeclined, errRefund, errCleanup},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := tt.run()
+				var m *MultiError
+				if !errors.As(err, &m) {
+					t.Fatalf("error = %v, want MultiError", err)
+				}
+				for _, want := range tt.wantErr {
+					if !errors.Is(err, want) {
+						t.Errorf("error = %v, want %v", err, want)
+					}
+				}
+			},
+		)
+	}
+}
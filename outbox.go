@@ -0,0 +1,225 @@
+package goTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OutboxMessage struct {
+	ID        string
+	Topic     string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Outbox records messages in the same database transaction as the state change
+// they announce. A relay later publishes the pending messages.
+type Outbox interface {
+	Add(ctx context.Context, tx *sql.Tx, msgs ...OutboxMessage) error
+	Pending(ctx context.Context, limit int) ([]OutboxMessage, error)
+	MarkPublished(ctx context.Context, id string) error
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+type PublisherFunc func(ctx context.Context, msg OutboxMessage) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+type PlaceholderStyle int
+
+const (
+	// QuestionPlaceholders uses ? placeholders, as MySQL and SQLite do.
+	QuestionPlaceholders PlaceholderStyle = iota
+	// DollarPlaceholders uses $1, $2, ... placeholders, as PostgreSQL does.
+	DollarPlaceholders
+)
+
+func (s PlaceholderStyle) placeholder(n int) string {
+	if s == DollarPlaceholders {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// SQLOutbox stores messages in a table with the following columns:
+//
+//	id           VARCHAR PRIMARY KEY
+//	topic        VARCHAR NOT NULL
+//	payload      BLOB
+//	created_at   TIMESTAMP NOT NULL
+//	published_at TIMESTAMP NULL
+type SQLOutbox struct {
+	db          *sql.DB
+	table       string
+	Placeholder PlaceholderStyle
+}
+
+func NewSQLOutbox(db *sql.DB, table string) *SQLOutbox {
+	return &SQLOutbox{db: db, table: table}
+}
+
+func (o *SQLOutbox) Add(ctx context.Context, tx *sql.Tx, msgs ...OutboxMessage) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (id, topic, payload, created_at) VALUES (%s)",
+		o.table, o.placeholders(4),
+	)
+	for _, msg := range msgs {
+		if msg.ID == "" {
+			msg.ID = newID()
+		}
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = time.Now().UTC()
+		}
+		if _, err := tx.ExecContext(ctx, query, msg.ID, msg.Topic, msg.Payload, msg.CreatedAt); err != nil {
+			return fmt.Errorf("outbox: add message to topic %q: %w", msg.Topic, err)
+		}
+	}
+	return nil
+}
+
+func (o *SQLOutbox) Pending(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	query := fmt.Sprintf(
+		"SELECT id, topic, payload, created_at FROM %s WHERE published_at IS NULL ORDER BY created_at LIMIT %s",
+		o.table, o.Placeholder.placeholder(1),
+	)
+	rows, err := o.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: query pending messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Payload, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("outbox: scan pending message: %w", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+func (o *SQLOutbox) MarkPublished(ctx context.Context, id string) error {
+	query := fmt.Sprintf(
+		"UPDATE %s SET published_at = %s WHERE id = %s",
+		o.table, o.Placeholder.placeholder(1), o.Placeholder.placeholder(2),
+	)
+	if _, err := o.db.ExecContext(ctx, query, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("outbox: mark message %q published: %w", id, err)
+	}
+	return nil
+}
+
+func (o *SQLOutbox) placeholders(n int) string {
+	p := make([]string, n)
+	for i := range p {
+		p[i] = o.Placeholder.placeholder(i + 1)
+	}
+	return strings.Join(p, ", ")
+}
+
+// OutboxRelay polls an Outbox and publishes its pending messages in order. A
+// message is retried with RetryOptions; if it still cannot be published the
+// batch stops so that later messages are not published before it.
+type OutboxRelay struct {
+	RetryOptions
+
+	outbox    Outbox
+	publisher Publisher
+
+	PollInterval time.Duration
+	BatchSize    int
+	// OnError, if set, is called with the errors Run encounters, which
+	// otherwise only delay the next poll.
+	OnError func(err error)
+}
+
+func NewOutboxRelay(outbox Outbox, publisher Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:       outbox,
+		publisher:    publisher,
+		PollInterval: 1 * time.Second,
+		BatchSize:    100,
+		RetryOptions: RetryOptions{
+			MaxRetries: 3,
+			Backoff: &ExponentialBackoff{
+				InitialInterval: 1 * time.Second,
+				MaxInterval:     30 * time.Second,
+				Multiplier:      2,
+				RandomFactor:    0.2,
+			},
+		},
+	}
+}
+
+// PublishPending publishes one batch of pending messages and returns how many
+// were published.
+func (r *OutboxRelay) PublishPending(ctx context.Context) (int, error) {
+	msgs, err := r.outbox.Pending(ctx, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range msgs {
+		msg := msg
+		err := RetryContext(ctx, func(ctx context.Context) error {
+			return r.publisher.Publish(ctx, msg)
+		}, r.RetryOptions)
+		if err != nil {
+			return i, fmt.Errorf("outbox: publish message %q: %w", msg.ID, err)
+		}
+		if err := r.outbox.MarkPublished(ctx, msg.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(msgs), nil
+}
+
+// Run publishes pending messages until ctx is done. Full batches are followed
+// immediately by the next one, otherwise Run waits PollInterval between polls.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	for {
+		n, err := r.PublishPending(ctx)
+		if err != nil && r.OnError != nil && !errors.Is(err, ctx.Err()) {
+			r.OnError(err)
+		}
+
+		if err != nil || n < r.BatchSize {
+			if err := sleepContext(ctx, r.PollInterval); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// OutboxUpdate returns an UpdateFunc for SagaTx that runs fn in a database
+// transaction and records the messages it returns in outbox before committing.
+func OutboxUpdate(db *sql.DB, outbox Outbox, fn func(tx *sql.Tx) ([]OutboxMessage, error)) UpdateFunc {
+	step := OutboxStep(db, outbox, func(_ context.Context, tx *sql.Tx) ([]OutboxMessage, error) {
+		return fn(tx)
+	})
+	return func() error { return step(context.Background()) }
+}
+
+// OutboxStep is the StepFunc counterpart of OutboxUpdate.
+func OutboxStep(db *sql.DB, outbox Outbox, fn func(ctx context.Context, tx *sql.Tx) ([]OutboxMessage, error)) StepFunc {
+	return TxStep(db, nil, func(ctx context.Context, tx *sql.Tx) error {
+		msgs, err := fn(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, msgs...)
+	})
+}
@@ -0,0 +1,218 @@
+package goTx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutboxUpdate(t *testing.T) {
+	errUpdate := errors.New("update error")
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+		wantLog []string
+	}{
+		{
+			name: "happypath",
+			wantLog: []string{
+				"begin",
+				"UPDATE accounts SET balance = balance - 10",
+				"INSERT INTO outbox (id, topic, payload, created_at) VALUES (?, ?, ?, ?) [msg-1 accounts.debited {\"amount\":10} 2023-01-02 03:04:05 +0000 UTC]",
+				"commit",
+			},
+		},
+		{
+			name:    "update-fails",
+			err:     errUpdate,
+			wantErr: true,
+			wantLog: []string{"begin", "UPDATE accounts SET balance = balance - 10", "rollback"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				db, fake := newFakeDB()
+				defer db.Close()
+
+				sagaTx := NewSagaTx(false)
+				sagaTx.Append(OutboxUpdate(db, NewSQLOutbox(db, "outbox"), func(tx *sql.Tx) ([]OutboxMessage, error) {
+					if _, err := tx.Exec("UPDATE accounts SET balance = balance - 10"); err != nil {
+						return nil, err
+					}
+					return []OutboxMessage{{
+						ID:        "msg-1",
+						Topic:     "accounts.debited",
+						Payload:   []byte(`{"amount":10}`),
+						CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+					}}, tt.err
+				}), func() error { return nil })
+
+				err := sagaTx.ExecuteAll()
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ExecuteAll() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if got := fake.entries(); fmt.Sprint(got) != fmt.Sprint(tt.wantLog) {
+					t.Errorf("statements = %q, want %q", got, tt.wantLog)
+				}
+			},
+		)
+	}
+}
+
+func TestSQLOutbox_Pending(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.query = func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"id", "topic", "payload", "created_at"}, [][]driver.Value{
+			{"msg-1", "accounts.debited", []byte("a"), created},
+			{"msg-2", "accounts.credited", []byte("b"), created},
+		}, nil
+	}
+
+	outbox := NewSQLOutbox(db, "outbox")
+	outbox.Placeholder = DollarPlaceholders
+
+	msgs, err := outbox.Pending(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[1].ID != "msg-2" || string(msgs[1].Payload) != "b" || !msgs[1].CreatedAt.Equal(created) {
+		t.Errorf("Pending() = %+v, unexpected messages", msgs)
+	}
+	if err := outbox.MarkPublished(context.Background(), "msg-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	log := fake.entries()
+	if !strings.HasSuffix(log[0], "WHERE published_at IS NULL ORDER BY created_at LIMIT $1 [10]") {
+		t.Errorf("pending query = %q", log[0])
+	}
+	if !strings.HasPrefix(log[1], "UPDATE outbox SET published_at = $1 WHERE id = $2 [") || !strings.HasSuffix(log[1], " msg-1]") {
+		t.Errorf("mark published statement = %q", log[1])
+	}
+}
+
+type memoryOutbox struct {
+	mu        sync.Mutex
+	msgs      []OutboxMessage
+	published map[string]bool
+}
+
+func (o *memoryOutbox) Add(_ context.Context, _ *sql.Tx, msgs ...OutboxMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.msgs = append(o.msgs, msgs...)
+	return nil
+}
+
+func (o *memoryOutbox) Pending(_ context.Context, limit int) ([]OutboxMessage, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var pending []OutboxMessage
+	for _, msg := range o.msgs {
+		if !o.published[msg.ID] && len(pending) < limit {
+			pending = append(pending, msg)
+		}
+	}
+	return pending, nil
+}
+
+func (o *memoryOutbox) MarkPublished(_ context.Context, id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.published[id] = true
+	return nil
+}
+
+func TestOutboxRelay_PublishPending(t *testing.T) {
+	errPublish := errors.New("publish error")
+	tests := []struct {
+		name          string
+		failures      map[string]int
+		wantPublished []string
+		wantErr       bool
+	}{
+		{
+			name:          "happypath",
+			wantPublished: []string{"m1", "m2", "m3"},
+		},
+		{
+			name:          "transient-failure",
+			failures:      map[string]int{"m2": 2},
+			wantPublished: []string{"m1", "m2", "m3"},
+		},
+		{
+			name:          "permanent-failure-stops-batch",
+			failures:      map[string]int{"m2": 10},
+			wantPublished: []string{"m1"},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				outbox := &memoryOutbox{published: make(map[string]bool)}
+				_ = outbox.Add(context.Background(), nil, OutboxMessage{ID: "m1"}, OutboxMessage{ID: "m2"}, OutboxMessage{ID: "m3"})
+
+				var published []string
+				attempts := make(map[string]int)
+				relay := NewOutboxRelay(outbox, PublisherFunc(func(_ context.Context, msg OutboxMessage) error {
+					attempts[msg.ID]++
+					if attempts[msg.ID] <= tt.failures[msg.ID] {
+						return errPublish
+					}
+					published = append(published, msg.ID)
+					return nil
+				}))
+				relay.RetryOptions = RetryOptions{MaxRetries: 3, Backoff: &ConstantBackoff{Interval: time.Millisecond}}
+
+				n, err := relay.PublishPending(context.Background())
+				if (err != nil) != tt.wantErr {
+					t.Errorf("PublishPending() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if n != len(tt.wantPublished) || fmt.Sprint(published) != fmt.Sprint(tt.wantPublished) {
+					t.Errorf("PublishPending() = %d, published %v, want %v", n, published, tt.wantPublished)
+				}
+				pending, _ := outbox.Pending(context.Background(), 10)
+				if len(pending) != 3-len(tt.wantPublished) {
+					t.Errorf("pending = %v, want %d messages", pending, 3-len(tt.wantPublished))
+				}
+			},
+		)
+	}
+}
+
+func TestOutboxRelay_Run(t *testing.T) {
+	outbox := &memoryOutbox{published: make(map[string]bool)}
+	_ = outbox.Add(context.Background(), nil, OutboxMessage{ID: "m1"}, OutboxMessage{ID: "m2"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := NewOutboxRelay(outbox, PublisherFunc(func(_ context.Context, msg OutboxMessage) error {
+		if msg.ID == "m2" {
+			cancel()
+		}
+		return nil
+	}))
+	relay.BatchSize = 1
+	relay.PollInterval = time.Millisecond
+
+	if err := relay.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if !outbox.published["m1"] || !outbox.published["m2"] {
+		t.Errorf("published = %v, want m1 and m2", outbox.published)
+	}
+}
@@ -0,0 +1,51 @@
+package goTx
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a step, compensation or chain operation that
+// panicked. The panic is recovered and handled like any other failure of the
+// function, so a panicking step makes the saga compensate.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic sets *err to a PanicError if the caller is panicking. It must be
+// deferred directly.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// safeStep returns fn with its panics recovered as a PanicError.
+func safeStep(fn StepFunc) StepFunc {
+	return func(ctx context.Context) (err error) {
+		defer recoverPanic(&err)
+		return fn(ctx)
+	}
+}
+
+// safeFunc returns fn with its panics recovered as a PanicError.
+func safeFunc(fn func() error) func() error {
+	return func() (err error) {
+		defer recoverPanic(&err)
+		return fn()
+	}
+}
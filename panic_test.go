@@ -0,0 +1,144 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func panickingStep(log *stepLog, name string) Step {
+	step := loggedStep(log, name, nil)
+	step.Update = func(context.Context) error {
+		var m map[string]int
+		m[name]++ // assignment to entry in nil map
+		return nil
+	}
+	return step
+}
+
+func TestSagaExecution_Panic(t *testing.T) {
+	errRefund := errors.New("refund rejected")
+
+	tests := []struct {
+		name      string
+		steps     func(log *stepLog) []Step
+		wantState ExecutionState
+		wantIs    error
+		wantLog   string
+	}{
+		{
+			name: "step panics",
+			steps: func(log *stepLog) []Step {
+				return []Step{loggedStep(log, "a", nil), panickingStep(log, "b")}
+			},
+			wantState: ExecutionCompensated,
+			wantLog:   "[a rollback a]",
+		},
+		{
+			name: "compensation panics with error",
+			steps: func(log *stepLog) []Step {
+				a := loggedStep(log, "a", nil)
+				a.Compensate = func(context.Context) error { panic(errRefund) }
+				return []Step{a, loggedStep(log, "b", errors.New("out of stock"))}
+			},
+			wantState: ExecutionFailed,
+			wantIs:    errRefund,
+			wantLog:   "[a b]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				log := &stepLog{}
+				builder := NewSagaBuilder("order")
+				for _, step := range tt.steps(log) {
+					builder.AppendStep(step)
+				}
+				def, err := builder.Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec := def.NewExecution()
+				err = exec.Execute(context.Background())
+				var perr *PanicError
+				if !errors.As(err, &perr) {
+					t.Fatalf("Execute() error = %v, want PanicError", err)
+				}
+				if !strings.Contains(string(perr.Stack), "panic_test.go") {
+					t.Errorf("PanicError stack does not show where the step panicked:\n%s", perr.Stack)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Errorf("Execute() error = %v, want %v", err, tt.wantIs)
+				}
+
+				if got := exec.State(); got != tt.wantState {
+					t.Errorf("State() = %s, want %s", got, tt.wantState)
+				}
+				if got := log.String(); got != tt.wantLog {
+					t.Errorf("log = %q, want %q", got, tt.wantLog)
+				}
+			},
+		)
+	}
+}
+
+func TestTx_Panic(t *testing.T) {
+	tests := []struct {
+		name  string
+		async bool
+	}{
+		{name: "sync"},
+		{name: "async", async: true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				compensated := make(chan struct{})
+				tx := NewSagaTx(tt.async)
+				tx.Append(
+					func() error { panic("boom") },
+					func() error { close(compensated); return nil },
+				)
+
+				err := tx.ExecuteAll()
+				select {
+				case <-compensated:
+				default:
+					t.Error("ExecuteAll() returned before the step was compensated")
+				}
+				var perr *PanicError
+				if !errors.As(err, &perr) || perr.Value != "boom" {
+					t.Errorf("ExecuteAll() error = %v, want PanicError", err)
+				}
+			},
+		)
+	}
+}
+
+func TestChain_Panic(t *testing.T) {
+	var cleanups stepLog
+	chain := NewChain(false)
+	chain.Append(
+		NewFallbackOperation("reserve", func(context.Context) error { panic("boom") }, func(context.Context) error { return nil }).
+			WithCleanup(func(context.Context) error { panic("cleanup boom") }),
+	)
+	chain.Append(
+		NewFallbackOperation("charge", func(context.Context) error { cleanups.add("charge"); return errors.New("declined") }),
+	)
+
+	results, err := chain.Execute(context.Background())
+	if err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+
+	reserve := results[0]
+	var perr *PanicError
+	if reserve.Alternate != 1 || len(reserve.Errors) != 1 || !errors.As(reserve.Errors[0], &perr) {
+		t.Errorf("reserve = %+v, want primary to fail with PanicError", reserve)
+	}
+	if !errors.As(reserve.CleanupErr, &perr) || perr.Value != "cleanup boom" {
+		t.Errorf("reserve cleanup error = %v, want PanicError", reserve.CleanupErr)
+	}
+}
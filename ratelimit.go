@@ -0,0 +1,125 @@
+package goTx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that limits how often steps are attempted. It
+// holds up to burst tokens and gains rate tokens per second; every attempt of
+// a rate-limited update or compensation, including retries, takes one token
+// and waits for it if the bucket is empty. A RateLimiter is safe for
+// concurrent use, so one limiter can be shared by the steps of many sagas that
+// call the same API.
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+	clock     Clock
+	observers []func(wait time.Duration)
+}
+
+// NewRateLimiter returns a full bucket of burst tokens that refills at rate
+// tokens per second. It panics if rate is not positive; a burst below 1 is
+// treated as 1.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 {
+		panic("goTx: rate limiter needs a positive rate")
+	}
+	burst = max(burst, 1)
+	clock := goroutineScheduler{}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// SetClock makes the limiter refill and wait on clock instead of the real
+// clock, for example the clock of a simulation.
+func (l *RateLimiter) SetClock(clock Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clock = clock
+	l.last = clock.Now()
+}
+
+// OnWait registers an observer that is called with the time every attempt
+// waited for its token, zero if a token was available right away.
+func (l *RateLimiter) OnWait(observer func(wait time.Duration)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.observers = append(l.observers, observer)
+}
+
+// Wait takes a token, waiting until one is available, and returns how long it
+// waited. If ctx is done first, the token is given back and Wait returns the
+// error of ctx.
+func (l *RateLimiter) Wait(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	clock := l.clock
+	l.refill(clock.Now())
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	observers := l.observers
+	l.mu.Unlock()
+
+	if wait > 0 {
+		if err := clock.Sleep(ctx, wait); err != nil {
+			l.mu.Lock()
+			l.tokens = min(l.tokens+1, l.burst)
+			l.mu.Unlock()
+			return 0, err
+		}
+	}
+
+	for _, observer := range observers {
+		observer(wait)
+	}
+	return wait, nil
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	if now.After(l.last) {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+		l.last = now
+	}
+}
+
+// limited returns fn waiting for a token of limiter, which may be nil, before
+// every call.
+func limited(limiter *RateLimiter, fn StepFunc) StepFunc {
+	if limiter == nil {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		if _, err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+		return fn(ctx)
+	}
+}
+
+// limitedFunc is limited for functions without a context.
+func limitedFunc(limiter *RateLimiter, fn func() error) func() error {
+	if limiter == nil {
+		return fn
+	}
+	return func() error {
+		return limited(limiter, func(context.Context) error { return fn() })(context.Background())
+	}
+}
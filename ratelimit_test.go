@@ -0,0 +1,255 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		burst    int
+		advance  []time.Duration
+		wantWait []time.Duration
+	}{
+		{
+			name:     "burst",
+			rate:     1,
+			burst:    3,
+			advance:  []time.Duration{0, 0, 0},
+			wantWait: []time.Duration{0, 0, 0},
+		},
+		{
+			name:     "empty bucket",
+			rate:     2,
+			burst:    1,
+			advance:  []time.Duration{0, 0, 0},
+			wantWait: []time.Duration{0, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:     "refill",
+			rate:     1,
+			burst:    1,
+			advance:  []time.Duration{0, time.Second, 250 * time.Millisecond},
+			wantWait: []time.Duration{0, 0, 750 * time.Millisecond},
+		},
+		{
+			name:     "refill is capped at burst",
+			rate:     1,
+			burst:    2,
+			advance:  []time.Duration{0, time.Hour, 0, 0},
+			wantWait: []time.Duration{0, 0, 0, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				clock := &fakeClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+				limiter := NewRateLimiter(tt.rate, tt.burst)
+				limiter.SetClock(clock)
+
+				var observed []time.Duration
+				limiter.OnWait(func(wait time.Duration) { observed = append(observed, wait) })
+
+				for i, advance := range tt.advance {
+					clock.now = clock.now.Add(advance)
+					wait, err := limiter.Wait(context.Background())
+					if err != nil {
+						t.Fatalf("Wait() error = %v", err)
+					}
+					if wait != tt.wantWait[i] {
+						t.Errorf("Wait() #%d = %v, want %v", i, wait, tt.wantWait[i])
+					}
+				}
+				if len(observed) != len(tt.wantWait) {
+					t.Fatalf("observed = %v, want %v", observed, tt.wantWait)
+				}
+				for i := range observed {
+					if observed[i] != tt.wantWait[i] {
+						t.Errorf("observed = %v, want %v", observed, tt.wantWait)
+					}
+				}
+			},
+		)
+	}
+}
+
+type cancelledClock struct {
+	fakeClock
+}
+
+func (c *cancelledClock) Sleep(context.Context, time.Duration) error {
+	return context.Canceled
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	clock := &cancelledClock{}
+	limiter := NewRateLimiter(1, 1)
+	limiter.SetClock(clock)
+	observed := 0
+	limiter.OnWait(func(time.Duration) { observed++ })
+
+	if _, err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	for range 2 {
+		if _, err := limiter.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Wait() error = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	// The cancelled waits gave their tokens back, so the next one waits for a
+	// single token again.
+	limiter.SetClock(&clock.fakeClock)
+	wait, err := limiter.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if wait != time.Second {
+		t.Errorf("Wait() = %v, want %v", wait, time.Second)
+	}
+	if observed != 2 {
+		t.Errorf("observed %d waits, want 2", observed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSagaExecution_RateLimiter(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name               string
+		sagaLimiter        bool
+		stepLimiter        bool
+		wantWait           time.Duration
+		wantCompensateWait time.Duration
+		wantSleeps         int
+	}{
+		{
+			name: "no limiter",
+		},
+		{
+			name:               "saga limiter",
+			sagaLimiter:        true,
+			wantWait:           2 * time.Second,
+			wantCompensateWait: time.Second,
+			wantSleeps:         3,
+		},
+		{
+			name:               "step limiter",
+			stepLimiter:        true,
+			wantCompensateWait: time.Second,
+			wantSleeps:         1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				clock := &fakeClock{}
+				limiter := NewRateLimiter(1, 1)
+				limiter.SetClock(clock)
+
+				log := &stepLog{}
+				a := loggedStep(log, "a", nil)
+				if tt.stepLimiter {
+					a.RateLimiter = limiter
+				}
+				attempts := 0
+				b := Step{
+					Name: "b",
+					Update: func(context.Context) error {
+						attempts++
+						return errUnavailable
+					},
+					Retry: &RetryOptions{MaxRetries: 2, Clock: clock},
+				}
+				builder := NewSagaBuilder("order").AppendStep(a).AppendStep(b)
+				if tt.sagaLimiter {
+					builder.WithRateLimiter(limiter)
+				}
+				def, err := builder.Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				exec, err := def.Execute(context.Background())
+				if !errors.Is(err, errUnavailable) {
+					t.Fatalf("Execute() error = %v, want %v", err, errUnavailable)
+				}
+				if attempts != 2 {
+					t.Errorf("attempts = %d, want 2", attempts)
+				}
+
+				report := exec.Report()
+				var wait, compensateWait time.Duration
+				for _, step := range report.Steps {
+					wait += step.RateLimitWait
+					compensateWait += step.CompensationRateLimitWait
+				}
+				if wait != tt.wantWait {
+					t.Errorf("RateLimitWait = %v, want %v", wait, tt.wantWait)
+				}
+				if compensateWait != tt.wantCompensateWait {
+					t.Errorf("CompensationRateLimitWait = %v, want %v", compensateWait, tt.wantCompensateWait)
+				}
+				if len(clock.sleeps) != tt.wantSleeps {
+					t.Errorf("sleeps = %v, want %d", clock.sleeps, tt.wantSleeps)
+				}
+			},
+		)
+	}
+}
+
+func TestRateLimiter_Shared(t *testing.T) {
+	clock := &fakeClock{}
+	limiter := NewRateLimiter(1, 2)
+	limiter.SetClock(clock)
+	var waits []time.Duration
+	limiter.OnWait(func(wait time.Duration) { waits = append(waits, wait) })
+
+	tx := NewSagaTx(false)
+	tx.SetRateLimiter(limiter)
+	tx.Append(func() error { return nil }, func() error { return nil })
+	tx.Append(func() error { return nil }, func() error { return nil })
+	if err := tx.ExecuteAll(); err != nil {
+		t.Fatalf("SagaTx.ExecuteAll() error = %v", err)
+	}
+
+	chain := NewChain(false)
+	chain.SetRateLimiter(limiter)
+	chain.Append(NewFallbackOperation("reserve", func(context.Context) error { return nil }))
+	if err := chain.ExecuteAll(); err != nil {
+		t.Fatalf("Chain.ExecuteAll() error = %v", err)
+	}
+
+	def, err := NewSagaBuilder("order").
+		AppendStep(loggedStep(&stepLog{}, "ship", nil)).
+		WithRateLimiter(limiter).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := def.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	// The saga takes the burst of two tokens, the chain and the saga
+	// definition wait for the next ones.
+	want := []time.Duration{0, 0, time.Second, time.Second}
+	if len(waits) != len(want) {
+		t.Fatalf("waits = %v, want %v", waits, want)
+	}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("waits = %v, want %v", waits, want)
+		}
+	}
+}
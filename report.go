@@ -0,0 +1,287 @@
+package goTx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type StepStatus int
+
+const (
+	StepPending StepStatus = iota
+	StepRunning
+	StepSucceeded
+	StepFailed
+	StepCompensating
+	StepCompensated
+	StepCompensationFailed
+	StepSkipped
+)
+
+func (s StepStatus) String() string {
+	switch s {
+	case StepPending:
+		return "pending"
+	case StepRunning:
+		return "running"
+	case StepSucceeded:
+		return "succeeded"
+	case StepFailed:
+		return "failed"
+	case StepCompensating:
+		return "compensating"
+	case StepCompensated:
+		return "compensated"
+	case StepCompensationFailed:
+		return "compensation-failed"
+	case StepSkipped:
+		return "skipped"
+	default:
+		return fmt.Sprintf("StepStatus(%d)", int(s))
+	}
+}
+
+type StepReport struct {
+	Name      string
+	Status    StepStatus
+	StartedAt time.Time
+	Attempts  int
+	Duration  time.Duration
+	Err       error
+	// Replayed is set if the outcome of the step was taken from the
+	// IdempotencyStore of the saga instead of invoking the step.
+	Replayed bool
+	// RateLimitWait is the time the attempts of the update waited for the
+	// RateLimiter of the step.
+	RateLimitWait time.Duration
+
+	CompensationStartedAt     time.Time
+	CompensationAttempts      int
+	CompensationDuration      time.Duration
+	CompensationErr           error
+	CompensationReplayed      bool
+	CompensationRateLimitWait time.Duration
+
+	// Child is the report of the nested execution for steps added with
+	// SagaBuilder.AppendSaga.
+	Child *ExecutionReport
+}
+
+// ExecutionReport is a snapshot of a SagaExecution. It can be taken at any time,
+// including while the execution is still running.
+type ExecutionReport struct {
+	ID         string
+	Saga       string
+	State      ExecutionState
+	Err        error
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Steps      []StepReport
+
+	// SuspendReason is the reason a suspended execution is waiting for an
+	// operator.
+	SuspendReason string
+}
+
+func (r *ExecutionReport) Duration() time.Duration {
+	if r.StartedAt.IsZero() || r.FinishedAt.IsZero() {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+func (r *ExecutionReport) Step(name string) (StepReport, bool) {
+	for _, step := range r.Steps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return StepReport{}, false
+}
+
+// StepsWithStatus returns the names of the steps currently in the given status,
+// in definition order.
+func (r *ExecutionReport) StepsWithStatus(status StepStatus) []string {
+	var names []string
+	for _, step := range r.Steps {
+		if step.Status == status {
+			names = append(names, step.Name)
+		}
+	}
+	return names
+}
+
+func (s ExecutionState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *ExecutionState) UnmarshalText(text []byte) error {
+	for state := ExecutionPending; state <= ExecutionSuspended; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown execution state %q", text)
+}
+
+func (s StepStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *StepStatus) UnmarshalText(text []byte) error {
+	for status := StepPending; status <= StepSkipped; status++ {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown step status %q", text)
+}
+
+type stepReportJSON struct {
+	Name      string        `json:"name"`
+	Status    StepStatus    `json:"status"`
+	StartedAt *time.Time    `json:"started_at,omitempty"`
+	Attempts  int           `json:"attempts"`
+	Duration  time.Duration `json:"duration"`
+	Err       string        `json:"error,omitempty"`
+	Replayed  bool          `json:"replayed,omitempty"`
+
+	RateLimitWait time.Duration `json:"rate_limit_wait,omitempty"`
+
+	CompensationStartedAt *time.Time    `json:"compensation_started_at,omitempty"`
+	CompensationAttempts  int           `json:"compensation_attempts,omitempty"`
+	CompensationDuration  time.Duration `json:"compensation_duration,omitempty"`
+	CompensationErr       string        `json:"compensation_error,omitempty"`
+	CompensationReplayed  bool          `json:"compensation_replayed,omitempty"`
+
+	CompensationRateLimitWait time.Duration `json:"compensation_rate_limit_wait,omitempty"`
+
+	Child *ExecutionReport `json:"child,omitempty"`
+}
+
+type executionReportJSON struct {
+	ID         string         `json:"id"`
+	Saga       string         `json:"saga"`
+	State      ExecutionState `json:"state"`
+	Err        string         `json:"error,omitempty"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Steps      []StepReport   `json:"steps"`
+
+	SuspendReason string `json:"suspend_reason,omitempty"`
+}
+
+// MarshalJSON encodes errors as their message; UnmarshalJSON decodes them as
+// errors with the same message.
+func (r StepReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(stepReportJSON{
+		Name:                      r.Name,
+		Status:                    r.Status,
+		StartedAt:                 timeOrNil(r.StartedAt),
+		Attempts:                  r.Attempts,
+		Duration:                  r.Duration,
+		Err:                       errorString(r.Err),
+		Replayed:                  r.Replayed,
+		RateLimitWait:             r.RateLimitWait,
+		CompensationStartedAt:     timeOrNil(r.CompensationStartedAt),
+		CompensationAttempts:      r.CompensationAttempts,
+		CompensationDuration:      r.CompensationDuration,
+		CompensationErr:           errorString(r.CompensationErr),
+		CompensationReplayed:      r.CompensationReplayed,
+		CompensationRateLimitWait: r.CompensationRateLimitWait,
+		Child:                     r.Child,
+	})
+}
+
+func (r *StepReport) UnmarshalJSON(data []byte) error {
+	var v stepReportJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = StepReport{
+		Name:                      v.Name,
+		Status:                    v.Status,
+		Attempts:                  v.Attempts,
+		Duration:                  v.Duration,
+		Err:                       stringError(v.Err),
+		Replayed:                  v.Replayed,
+		RateLimitWait:             v.RateLimitWait,
+		CompensationAttempts:      v.CompensationAttempts,
+		CompensationDuration:      v.CompensationDuration,
+		CompensationErr:           stringError(v.CompensationErr),
+		CompensationReplayed:      v.CompensationReplayed,
+		CompensationRateLimitWait: v.CompensationRateLimitWait,
+		Child:                     v.Child,
+	}
+	if v.StartedAt != nil {
+		r.StartedAt = *v.StartedAt
+	}
+	if v.CompensationStartedAt != nil {
+		r.CompensationStartedAt = *v.CompensationStartedAt
+	}
+	return nil
+}
+
+func (r ExecutionReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(executionReportJSON{
+		ID:         r.ID,
+		Saga:       r.Saga,
+		State:      r.State,
+		Err:        errorString(r.Err),
+		StartedAt:  timeOrNil(r.StartedAt),
+		FinishedAt: timeOrNil(r.FinishedAt),
+		Steps:      r.Steps,
+
+		SuspendReason: r.SuspendReason,
+	})
+}
+
+func (r *ExecutionReport) UnmarshalJSON(data []byte) error {
+	var v executionReportJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = ExecutionReport{
+		ID:    v.ID,
+		Saga:  v.Saga,
+		State: v.State,
+		Err:   stringError(v.Err),
+		Steps: v.Steps,
+
+		SuspendReason: v.SuspendReason,
+	}
+	if v.StartedAt != nil {
+		r.StartedAt = *v.StartedAt
+	}
+	if v.FinishedAt != nil {
+		r.FinishedAt = *v.FinishedAt
+	}
+	return nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func stringError(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,105 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSagaExecution_Report(t *testing.T) {
+	errStep := errors.New("step error")
+	flaky := 0
+
+	child, err := NewSagaBuilder("child").
+		Append("c1", func() error { return nil }, func() error { return nil }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, err := NewSagaBuilder("report").
+		Append("flaky", func() error {
+			flaky++
+			if flaky < 3 {
+				return errStep
+			}
+			return nil
+		}, func() error { return nil }).
+		AppendSaga("child", child).
+		Append("broken-compensation", func() error { return nil }, func() error { return errStep }).
+		Append("fails", func() error { return errStep }, func() error { return nil }).
+		Append("never-runs", func() error { return nil }, func() error { return nil }).
+		WithRetries(RetryOptions{MaxRetries: 3, Backoff: &ConstantBackoff{Interval: time.Millisecond}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := def.NewExecution()
+	if got := exec.Report().StepsWithStatus(StepPending); len(got) != 5 {
+		t.Errorf("pending steps before Execute() = %v, want all 5", got)
+	}
+	if err := exec.Execute(context.Background()); err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+
+	report := exec.Report()
+	if report.ID != exec.ID() || report.Saga != "report" || report.State != ExecutionFailed {
+		t.Errorf("Report() = %+v, unexpected header", report)
+	}
+	if report.Duration() <= 0 {
+		t.Errorf("Duration() = %v, want > 0", report.Duration())
+	}
+
+	tests := []struct {
+		step         string
+		wantStatus   StepStatus
+		wantAttempts int
+		wantErr      bool
+		wantCompErr  bool
+	}{
+		{step: "flaky", wantStatus: StepSucceeded, wantAttempts: 3},
+		{step: "child", wantStatus: StepSucceeded, wantAttempts: 1},
+		{step: "broken-compensation", wantStatus: StepCompensationFailed, wantAttempts: 1, wantCompErr: true},
+		{step: "fails", wantStatus: StepFailed, wantAttempts: 3, wantErr: true},
+		{step: "never-runs", wantStatus: StepPending, wantAttempts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.step, func(t *testing.T) {
+				step, ok := report.Step(tt.step)
+				if !ok {
+					t.Fatalf("Step(%q) not found", tt.step)
+				}
+				if step.Status != tt.wantStatus {
+					t.Errorf("Status = %v, want %v", step.Status, tt.wantStatus)
+				}
+				if step.Attempts != tt.wantAttempts {
+					t.Errorf("Attempts = %d, want %d", step.Attempts, tt.wantAttempts)
+				}
+				if (step.Err != nil) != tt.wantErr {
+					t.Errorf("Err = %v, wantErr %v", step.Err, tt.wantErr)
+				}
+				if (step.CompensationErr != nil) != tt.wantCompErr {
+					t.Errorf("CompensationErr = %v, wantCompErr %v", step.CompensationErr, tt.wantCompErr)
+				}
+			},
+		)
+	}
+
+	childStep, _ := report.Step("child")
+	if childStep.Child == nil || childStep.Child.State != ExecutionSucceeded {
+		t.Errorf("child report = %+v, want succeeded child execution", childStep.Child)
+	}
+
+	if err := exec.Compensate(context.Background()); err == nil {
+		t.Fatal("Compensate() error = nil, want error")
+	}
+	if got, _ := exec.Report().Step("broken-compensation"); got.CompensationAttempts != 2 {
+		t.Errorf("CompensationAttempts = %d, want 2", got.CompensationAttempts)
+	}
+	if got := fmt.Sprint(exec.Report().StepsWithStatus(StepCompensated)); got != "[]" {
+		t.Errorf("compensated steps = %v, want none", got)
+	}
+}
@@ -1,23 +1,63 @@
 package goTx
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 )
 
+// UnlimitedRetries as MaxRetries retries until the function succeeds, fails
+// with an unrecoverable error or the context is done. It should be used with a
+// backoff capped by a maximum interval.
+const UnlimitedRetries = -1
+
 type RetryOptions struct {
+	// MaxRetries is the number of attempts, the first one included. A value
+	// below 1 makes a single attempt; UnlimitedRetries retries without limit.
 	MaxRetries int
 	Backoff    Backoff
 
 	UnrecoverableErrors []error
+
+	// MaxInterval, if set, caps the time between attempts, including the
+	// delays asked for by a RetryAfterError. Otherwise the MaxInterval of an
+	// ExponentialBackoff caps them, and DefaultMaxRetryAfter caps the delays
+	// asked for by a RetryAfterError.
+	MaxInterval time.Duration
+	// Clock, if set, waits between attempts instead of the real clock.
+	Clock Clock
+	// OnRetry, if set, is called after every failed attempt that is retried,
+	// with the number of the attempt, its error and the time until the next
+	// attempt.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// DefaultMaxRetryAfter caps the delay asked for by a RetryAfterError when
+// neither RetryOptions.MaxInterval nor an ExponentialBackoff sets a maximum
+// interval, so that a server cannot stall a retry indefinitely.
+const DefaultMaxRetryAfter = time.Minute
+
+// RetryAfterError is implemented by errors that tell how long to wait before
+// the next attempt, such as an HTTP 429 or 503 response with a Retry-After
+// header. A positive RetryAfter replaces the interval of the backoff.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
 }
 
 type Backoff interface {
 	NextInterval() time.Duration
 }
 
+// cloner is implemented by the stateful backoffs of this package so that every
+// Retry call starts from the initial interval and concurrent retries sharing the
+// same RetryOptions do not race on the backoff state.
+type cloner interface {
+	clone() Backoff
+}
+
 type ConstantBackoff struct {
 	Interval time.Duration
 }
@@ -26,12 +66,19 @@ func (b *ConstantBackoff) NextInterval() time.Duration {
 	return b.Interval
 }
 
+func (b *ConstantBackoff) clone() Backoff {
+	c := *b
+	return &c
+}
+
 type ExponentialBackoff struct {
 	InitialInterval time.Duration
 	MaxInterval     time.Duration
 	Multiplier      float64
 	RandomFactor    float64
 	CurrentInterval time.Duration
+
+	random func() float64
 }
 
 func (b *ExponentialBackoff) NextInterval() time.Duration {
@@ -41,7 +88,11 @@ func (b *ExponentialBackoff) NextInterval() time.Duration {
 	}
 	next := time.Duration(float64(b.CurrentInterval) * b.Multiplier)
 	if b.RandomFactor > 0 {
-		jitter := (2*rand.Float64() - 1) * b.RandomFactor
+		random := rand.Float64
+		if b.random != nil {
+			random = b.random
+		}
+		jitter := (2*random() - 1) * b.RandomFactor
 		next = time.Duration(float64(next) * (1 + jitter))
 	}
 	if next > b.MaxInterval {
@@ -51,19 +102,156 @@ func (b *ExponentialBackoff) NextInterval() time.Duration {
 	return next
 }
 
+func (b *ExponentialBackoff) clone() Backoff {
+	c := *b
+	c.CurrentInterval = 0
+	return &c
+}
+
 func Retry(fn func() error, options RetryOptions) error {
-	var err error
-	for i := 0; i < options.MaxRetries; i++ {
-		if err = fn(); err == nil {
-			return nil
-		} else if options.UnrecoverableErrors != nil && len(options.UnrecoverableErrors) > 0 {
-			for _, e := range options.UnrecoverableErrors {
-				if errors.Is(err, e) {
-					return fmt.Errorf("unrecoverable error: %v", err)
-				}
+	return RetryContext(context.Background(), func(context.Context) error { return fn() }, options)
+}
+
+// RetryContext is like Retry but passes ctx to fn and stops waiting for the next
+// attempt as soon as ctx is done.
+func RetryContext(ctx context.Context, fn func(ctx context.Context) error, options RetryOptions) error {
+	return NewRetrier(options).Do(ctx, fn)
+}
+
+// RetryValue is like RetryContext for functions that return a value. It
+// returns the value of the attempt that succeeded, or the zero value of T if
+// none did.
+func RetryValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), options RetryOptions) (T, error) {
+	return DoValue(ctx, NewRetrier(options), fn)
+}
+
+// Retrier retries functions with the same RetryOptions, so that a retry policy
+// can be built once and shared. It is safe for concurrent use; every call
+// starts from the initial interval of the backoff.
+type Retrier struct {
+	options RetryOptions
+}
+
+func NewRetrier(options RetryOptions) *Retrier {
+	return &Retrier{options: options}
+}
+
+// Options returns the options of the retrier.
+func (r *Retrier) Options() RetryOptions {
+	return r.options
+}
+
+// Do calls fn until it succeeds, fails with an unrecoverable error or runs out
+// of retries, waiting between attempts as the backoff says.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	options := r.options
+	backoff := options.Backoff
+	if c, ok := backoff.(cloner); ok {
+		backoff = c.clone()
+		if b, ok := backoff.(*ExponentialBackoff); ok {
+			if rnd, ok := options.Clock.(randomSource); ok {
+				b.random = rnd.Float64
 			}
 		}
-		time.Sleep(options.Backoff.NextInterval())
 	}
-	return fmt.Errorf("error after %d retries: %v", options.MaxRetries, err)
+	sleep := sleepContext
+	if options.Clock != nil {
+		sleep = options.Clock.Sleep
+	}
+
+	unlimited := options.MaxRetries < 0
+	attempts := options.MaxRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var (
+		err error
+		i   int
+	)
+	for ; unlimited || i < attempts; i++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		} else if isUnrecoverable(err, options.UnrecoverableErrors) {
+			return fmt.Errorf("unrecoverable error: %w", err)
+		}
+		if !unlimited && i == attempts-1 {
+			break
+		}
+
+		var interval time.Duration
+		if backoff != nil {
+			interval = backoff.NextInterval()
+		}
+		var hint RetryAfterError
+		hinted := errors.As(err, &hint) && hint.RetryAfter() > 0
+		if hinted {
+			interval = hint.RetryAfter()
+		}
+		limit := maxInterval(options, backoff)
+		if hinted && limit <= 0 {
+			limit = DefaultMaxRetryAfter
+		}
+		if limit > 0 && interval > limit {
+			interval = limit
+		}
+		if options.OnRetry != nil {
+			options.OnRetry(i+1, err, interval)
+		}
+		if !unlimited && backoff == nil && !hinted {
+			continue
+		}
+		if werr := sleep(ctx, interval); werr != nil {
+			return fmt.Errorf("retry interrupted after %d attempts: %w", i+1, err)
+		}
+	}
+	return fmt.Errorf("error after %d attempts: %w", i+1, err)
+}
+
+// DoValue is like Retrier.Do for functions that return a value. It returns the
+// value of the attempt that succeeded, or the zero value of T if none did.
+func DoValue[T any](ctx context.Context, r *Retrier, fn func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	err := r.Do(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err == nil {
+			value = v
+		}
+		return err
+	})
+	return value, err
+}
+
+func maxInterval(options RetryOptions, backoff Backoff) time.Duration {
+	if options.MaxInterval > 0 {
+		return options.MaxInterval
+	}
+	if b, ok := backoff.(*ExponentialBackoff); ok {
+		return b.MaxInterval
+	}
+	return 0
+}
+
+func isUnrecoverable(err error, unrecoverable []error) bool {
+	for _, e := range unrecoverable {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -0,0 +1,370 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetryContext(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name         string
+		options      RetryOptions
+		failures     int
+		failWith     error
+		timeout      time.Duration
+		wantAttempts int
+		wantErr      error
+		wantMsg      string
+	}{
+		{
+			name:         "succeeds after retries",
+			options:      RetryOptions{MaxRetries: 3},
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up",
+			options:      RetryOptions{MaxRetries: 3},
+			failures:     5,
+			wantAttempts: 3,
+			wantErr:      errTemporary,
+			wantMsg:      "error after 3 attempts: temporary",
+		},
+		{
+			name:         "no retries",
+			options:      RetryOptions{MaxRetries: 0},
+			failures:     5,
+			wantAttempts: 1,
+			wantErr:      errTemporary,
+			wantMsg:      "error after 1 attempts: temporary",
+		},
+		{
+			name:         "unrecoverable",
+			options:      RetryOptions{MaxRetries: 3, UnrecoverableErrors: []error{errFatal}},
+			failures:     5,
+			failWith:     errFatal,
+			wantAttempts: 1,
+			wantErr:      errFatal,
+		},
+		{
+			name:         "unlimited",
+			options:      RetryOptions{MaxRetries: UnlimitedRetries, Backoff: &ConstantBackoff{Interval: time.Microsecond}},
+			failures:     50,
+			wantAttempts: 51,
+		},
+		{
+			name:         "unlimited until unrecoverable",
+			options:      RetryOptions{MaxRetries: UnlimitedRetries, UnrecoverableErrors: []error{errFatal}},
+			failures:     -1,
+			failWith:     errFatal,
+			wantAttempts: 1,
+			wantErr:      errFatal,
+		},
+		{
+			name:         "unlimited until context done",
+			options:      RetryOptions{MaxRetries: UnlimitedRetries, Backoff: &ConstantBackoff{Interval: 10 * time.Millisecond}},
+			failures:     -1,
+			timeout:      25 * time.Millisecond,
+			wantAttempts: 3,
+			wantErr:      errTemporary,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				if tt.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+					defer cancel()
+				}
+				failWith := tt.failWith
+				if failWith == nil {
+					failWith = errTemporary
+				}
+
+				attempts := 0
+				err := RetryContext(ctx, func(context.Context) error {
+					attempts++
+					if tt.failures < 0 || attempts <= tt.failures {
+						return failWith
+					}
+					return nil
+				}, tt.options)
+				if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+					t.Errorf("RetryContext() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+					t.Errorf("RetryContext() error = %q, want %q", err.Error(), tt.wantMsg)
+				}
+				if tt.timeout > 0 {
+					if attempts < tt.wantAttempts-1 || attempts > tt.wantAttempts+1 {
+						t.Errorf("attempts = %d, want about %d", attempts, tt.wantAttempts)
+					}
+				} else if attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+			},
+		)
+	}
+}
+
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(_ context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) Float64() float64 {
+	return 1
+}
+
+func TestRetryContext_Clock(t *testing.T) {
+	clock := &fakeClock{}
+	options := RetryOptions{
+		MaxRetries: 4,
+		Backoff: &ExponentialBackoff{
+			InitialInterval: time.Hour,
+			MaxInterval:     10 * time.Hour,
+			Multiplier:      2,
+			RandomFactor:    0.5,
+		},
+		Clock: clock,
+	}
+
+	err := RetryContext(context.Background(), func(context.Context) error { return errors.New("unavailable") }, options)
+	if err == nil {
+		t.Fatal("RetryContext() error = nil, want error")
+	}
+
+	// The jitter of the clock is always +50%.
+	want := []time.Duration{time.Hour, 3 * time.Hour, 9 * time.Hour}
+	if len(clock.sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	for i := range want {
+		if clock.sleeps[i] != want[i] {
+			t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+		}
+	}
+}
+
+type retryAfterError struct {
+	wait time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return "too many requests"
+}
+
+func (e *retryAfterError) RetryAfter() time.Duration {
+	return e.wait
+}
+
+func TestRetryContext_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   RetryOptions
+		err       error
+		wantSleep time.Duration
+	}{
+		{
+			name:      "hint replaces backoff",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Second}},
+			err:       &retryAfterError{wait: 5 * time.Second},
+			wantSleep: 5 * time.Second,
+		},
+		{
+			name:      "wrapped hint",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Second}},
+			err:       fmt.Errorf("charge: %w", &retryAfterError{wait: 5 * time.Second}),
+			wantSleep: 5 * time.Second,
+		},
+		{
+			name:      "hint without backoff",
+			err:       &retryAfterError{wait: 5 * time.Second},
+			wantSleep: 5 * time.Second,
+		},
+		{
+			name:      "hint capped by max interval",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Second}, MaxInterval: 3 * time.Second},
+			err:       &retryAfterError{wait: time.Hour},
+			wantSleep: 3 * time.Second,
+		},
+		{
+			name:      "hint capped by exponential backoff",
+			options:   RetryOptions{Backoff: &ExponentialBackoff{InitialInterval: time.Second, MaxInterval: 10 * time.Second, Multiplier: 2}},
+			err:       &retryAfterError{wait: time.Hour},
+			wantSleep: 10 * time.Second,
+		},
+		{
+			name:      "hint capped by default with constant backoff",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Second}},
+			err:       &retryAfterError{wait: 24 * time.Hour},
+			wantSleep: DefaultMaxRetryAfter,
+		},
+		{
+			name:      "hint capped by default without backoff",
+			err:       &retryAfterError{wait: 24 * time.Hour},
+			wantSleep: DefaultMaxRetryAfter,
+		},
+		{
+			name:      "constant backoff not capped by default",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Hour}},
+			err:       errors.New("unavailable"),
+			wantSleep: time.Hour,
+		},
+		{
+			name:      "no hint",
+			options:   RetryOptions{Backoff: &ConstantBackoff{Interval: time.Second}},
+			err:       &retryAfterError{},
+			wantSleep: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				clock := &fakeClock{}
+				options := tt.options
+				options.MaxRetries = 2
+				options.Clock = clock
+
+				attempts := 0
+				err := RetryContext(context.Background(), func(context.Context) error {
+					attempts++
+					if attempts == 1 {
+						return tt.err
+					}
+					return nil
+				}, options)
+				if err != nil {
+					t.Fatalf("RetryContext() error = %v", err)
+				}
+				if want := []time.Duration{tt.wantSleep}; !slices.Equal(clock.sleeps, want) {
+					t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+				}
+			},
+		)
+	}
+}
+
+func TestRetryValue(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name         string
+		failures     int
+		failWith     error
+		wantValue    string
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "first attempt",
+			wantValue:    "order-1",
+			wantAttempts: 1,
+		},
+		{
+			name:         "after retries",
+			failures:     2,
+			failWith:     errTemporary,
+			wantValue:    "order-1",
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			failures:     3,
+			failWith:     errTemporary,
+			wantErr:      errTemporary,
+			wantAttempts: 3,
+		},
+		{
+			name:         "unrecoverable",
+			failures:     1,
+			failWith:     errFatal,
+			wantErr:      errFatal,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				attempts := 0
+				fetch := func(context.Context) (string, error) {
+					attempts++
+					if attempts <= tt.failures {
+						return "partial", tt.failWith
+					}
+					return "order-1", nil
+				}
+				options := RetryOptions{MaxRetries: 3, UnrecoverableErrors: []error{errFatal}}
+
+				got, err := RetryValue(context.Background(), fetch, options)
+				if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+					t.Errorf("RetryValue() error = %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.wantValue {
+					t.Errorf("RetryValue() = %q, want %q", got, tt.wantValue)
+				}
+				if attempts != tt.wantAttempts {
+					t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+				}
+			},
+		)
+	}
+}
+
+func TestRetrier(t *testing.T) {
+	clock := &fakeClock{}
+	var retries []string
+	retrier := NewRetrier(
+		RetryOptions{
+			MaxRetries: 3,
+			Backoff:    &ExponentialBackoff{InitialInterval: time.Second, MaxInterval: time.Minute, Multiplier: 2},
+			Clock:      clock,
+			OnRetry: func(attempt int, err error, wait time.Duration) {
+				retries = append(retries, fmt.Sprintf("%d %v %v", attempt, err, wait))
+			},
+		},
+	)
+
+	// Every call starts from the initial interval of the shared backoff.
+	for range 2 {
+		attempts := 0
+		n, err := DoValue(context.Background(), retrier, func(context.Context) (int, error) {
+			attempts++
+			if attempts < 3 {
+				return 0, errors.New("unavailable")
+			}
+			return attempts, nil
+		})
+		if err != nil || n != 3 {
+			t.Fatalf("DoValue() = %d, %v, want 3", n, err)
+		}
+	}
+
+	want := []string{"1 unavailable 1s", "2 unavailable 2s", "1 unavailable 1s", "2 unavailable 2s"}
+	if !slices.Equal(retries, want) {
+		t.Errorf("OnRetry calls = %q, want %q", retries, want)
+	}
+	if got, want := clock.sleeps, []time.Duration{time.Second, 2 * time.Second, time.Second, 2 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
@@ -1,10 +1,10 @@
 package goTx
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 type (
@@ -29,7 +29,17 @@ type SagaTx struct {
 	lock           sync.Mutex
 	completedCount int
 	completedErr   error
-	errs           error
+	// ran is the number of steps that ran in the last execution.
+	ran int
+
+	name        string
+	id          string
+	deadLetters DeadLetterStore
+
+	compensationRetries *RetryOptions
+
+	scheduler   Scheduler
+	rateLimiter *RateLimiter
 }
 
 func NewSagaTx(async bool) *SagaTx {
@@ -59,24 +69,102 @@ func (t *SagaTx) AppendUnrecoverableErrors(errs ...error) {
 	t.UnrecoverableErrors = errs
 }
 
+// SetRetries enables or disables retrying failed updates with RetryOptions.
+func (t *SagaTx) SetRetries(enabled bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.retries = enabled
+}
+
+// SetRateLimiter makes every attempt of the update and compensation functions,
+// including retries, wait for a token of limiter.
+func (t *SagaTx) SetRateLimiter(limiter *RateLimiter) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.rateLimiter = limiter
+}
+
+// SetDeadLetterStore makes a failing compensation park a DeadLetter in store
+// and return an error instead of panicking. The compensations that did not run
+// are kept, so calling Compensate or RetryDeadLetter retries the rollback from
+// the compensation that failed; the dead letter is removed once the rollback
+// completes.
+func (t *SagaTx) SetDeadLetterStore(store DeadLetterStore) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.deadLetters = store
+}
+
+// SetName names the saga in its dead letters. The default name is "saga".
+func (t *SagaTx) SetName(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.name = name
+}
+
+// ID returns the ID of the saga, which is the ID of its dead letters.
+func (t *SagaTx) ID() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.id == "" {
+		t.id = newID()
+	}
+	return t.id
+}
+
+// RetryDeadLetter retries the rollback of the saga from the compensation that
+// failed, like Compensate, and removes dl from store once it completes. dl must
+// be a dead letter of this saga; the functions of a SagaTx only live in memory,
+// so its dead letters cannot be retried by anything else.
+func (t *SagaTx) RetryDeadLetter(ctx context.Context, store DeadLetterStore, dl DeadLetter) error {
+	if !dl.SagaTx || dl.ID != t.ID() {
+		return fmt.Errorf("%w: %s is not a dead letter of SagaTx %s", ErrDeadLetterMismatch, dl.ID, t.ID())
+	}
+	if err := t.Compensate(); err != nil {
+		return err
+	}
+	return store.DeleteDeadLetter(ctx, dl.ID)
+}
+
+// SetCompensationRetries makes the rollback retry failed compensations with
+// options, independently of the retries of the update functions.
+func (t *SagaTx) SetCompensationRetries(options RetryOptions) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.compensationRetries = &options
+}
+
+// SetScheduler makes an async saga run its steps with scheduler, and makes
+// retries wait on its clock unless their options have a clock of their own.
+func (t *SagaTx) SetScheduler(scheduler Scheduler) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.scheduler = scheduler
+}
+
 func (t *SagaTx) Append(txFunc UpdateFunc, rollbackFunc CompensateFunc) {
 	t.txFuncs = append(t.txFuncs, txFunc)
 	t.rollbackFuncs = append(t.rollbackFuncs, rollbackFunc)
 }
 
+func (t *SagaTx) AppendSaga(child *SagaTx) {
+	t.Append(child.ExecuteAll, child.Compensate)
+}
+
 func (t *SagaTx) Do(txFunc UpdateFunc, rollbackFunc CompensateFunc) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
 	t.completedErr = nil
 
-	var err error
-	if t.retries {
-		err = Retry(txFunc, t.RetryOptions)
-	} else {
-		err = txFunc()
-	}
-
+	err := t.update(txFunc)
 	if err != nil {
 		t.Append(func() error { return nil }, rollbackFunc)
 	}
@@ -94,29 +182,14 @@ func (t *SagaTx) ExecuteAll() error {
 
 	t.completedCount = 0
 	t.completedErr = nil
+	t.ran = 0
 
-	for _, txFunc := range t.txFuncs {
-		txF := txFunc
-		if t.async {
-			go func(txF UpdateFunc) {
-				var err error
-				if t.retries {
-					err = Retry(txF, t.RetryOptions)
-				} else {
-					err = txF()
-				}
-				t.handleCompletion(err)
-			}(txF)
-		} else {
-			var err error
-			if t.retries {
-				err = Retry(txF, t.RetryOptions)
-			} else {
-				err = txF()
-			}
-			t.handleCompletion(err)
-		}
+	if t.async {
+		return t.executeAsync()
+	}
 
+	for _, txFunc := range t.txFuncs {
+		t.handleCompletion(t.update(txFunc))
 		if t.completedErr != nil {
 			return t.completedErr
 		}
@@ -125,25 +198,144 @@ func (t *SagaTx) ExecuteAll() error {
 	return nil
 }
 
+// executeAsync runs all update functions concurrently and waits until they
+// returned. If any of them failed, all of them are compensated.
+func (t *SagaTx) executeAsync() error {
+	errs := make([]error, len(t.txFuncs))
+	tasks := make([]func(), len(t.txFuncs))
+	for i, txFunc := range t.txFuncs {
+		tasks[i] = func() { errs[i] = t.update(txFunc) }
+	}
+	schedulerOrDefault(t.scheduler).Go(tasks...)()
+
+	t.completedCount = len(t.txFuncs)
+	t.ran = t.completedCount
+	t.completedErr = joinErrors(errs...)
+	if t.completedErr != nil {
+		if err := t.rollback(); err != nil {
+			t.completedErr = joinErrors(t.completedErr, err)
+		}
+	}
+	return t.completedErr
+}
+
+// update runs txFunc, retrying it if retries are enabled.
+func (t *SagaTx) update(txFunc UpdateFunc) error {
+	txFunc = limitedFunc(t.rateLimiter, safeFunc(txFunc))
+	if t.retries {
+		return Retry(txFunc, withClock(t.RetryOptions, t.scheduler))
+	}
+	return txFunc()
+}
+
+func (t *SagaTx) Compensate() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.rollback()
+}
+
 func (t *SagaTx) handleCompletion(err error) {
 	if err != nil {
-		t.completedErr = err
-		t.errs = errors.Wrap(t.completedErr, err.Error())
+		t.completedErr = joinErrors(t.completedErr, err)
 	}
 
 	t.completedCount++
+	t.ran = t.completedCount
 
 	if t.completedErr != nil {
-		t.rollback()
+		if err := t.rollback(); err != nil {
+			t.completedErr = joinErrors(t.completedErr, err)
+		}
 	}
 }
 
-func (t *SagaTx) rollback() {
+func (t *SagaTx) rollback() error {
 	for i := t.completedCount - 1; i >= 0; i-- {
 		rollbackFunc := t.rollbackFuncs[i]
-		err := rollbackFunc()
+		attempts := 0
+		compensate := func() error {
+			attempts++
+			return limitedFunc(t.rateLimiter, safeFunc(rollbackFunc))()
+		}
+
+		var err error
+		if t.compensationRetries != nil {
+			err = Retry(compensate, withClock(*t.compensationRetries, t.scheduler))
+		} else {
+			err = compensate()
+		}
 		if err != nil {
-			panic(err)
+			if t.deadLetters == nil {
+				panic(err)
+			}
+			t.completedCount = i + 1
+			return t.deadLetter(i, attempts, err)
+		}
+	}
+	t.completedCount = 0
+
+	if t.deadLetters != nil && t.id != "" {
+		return t.deadLetters.DeleteDeadLetter(context.Background(), t.id)
+	}
+	return nil
+}
+
+func (t *SagaTx) deadLetter(i, attempts int, err error) error {
+	if t.id == "" {
+		t.id = newID()
+	}
+
+	name := t.name
+	if name == "" {
+		name = "saga"
+	}
+	now := time.Now()
+	step := fmt.Sprintf("step %d", i+1)
+	dl := DeadLetter{
+		ID:          t.id,
+		ExecutionID: t.id,
+		Saga:        name,
+		SagaTx:      true,
+		Step:        step,
+		Cause:       t.completedErr,
+		Err:         err,
+		Attempts:    attempts,
+		CreatedAt:   now,
+		Report:      t.report(name, i, attempts, err, now),
+	}
+	err = fmt.Errorf("compensation of %s failed: %w", step, err)
+	if serr := t.deadLetters.AddDeadLetter(context.Background(), dl); serr != nil {
+		return fmt.Errorf("%w; saving dead letter: %w", err, serr)
+	}
+	return err
+}
+
+// report describes the saga whose compensation of step i failed with err: the
+// steps before it still have to be compensated, the steps after it that ran
+// were compensated.
+func (t *SagaTx) report(name string, i, attempts int, err error, now time.Time) *ExecutionReport {
+	r := &ExecutionReport{
+		ID:         t.id,
+		Saga:       name,
+		State:      ExecutionFailed,
+		Err:        t.completedErr,
+		FinishedAt: now,
+		Steps:      make([]StepReport, len(t.txFuncs)),
+	}
+	for j := range r.Steps {
+		step := &r.Steps[j]
+		step.Name = fmt.Sprintf("step %d", j+1)
+		switch {
+		case j < i:
+			step.Status = StepSucceeded
+		case j == i:
+			step.Status = StepCompensationFailed
+			step.CompensationAttempts = attempts
+			step.CompensationErr = err
+		case j < t.ran:
+			step.Status = StepCompensated
 		}
 	}
+	return r
 }
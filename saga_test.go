@@ -186,10 +186,10 @@ func TestTx_Execute(t1 *testing.T) {
 					if tt.fields.async {
 						wg.Add(1)
 					}
-					t.AppendFunc(txFunc, tt.fields.rollbackFuncs[i])
+					t.Append(txFunc, tt.fields.rollbackFuncs[i])
 				}
 
-				err := t.ExecuteFuncs()
+				err := t.ExecuteAll()
 				if (err != nil) != tt.wantErr {
 					t1.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
 				}
@@ -320,15 +320,15 @@ func TestTx_ExecuteFunc(t1 *testing.T) {
 
 				defer t.rollback()
 				for i, txFunc := range tt.fields.txFuncs {
-					t.AppendFunc(txFunc, tt.fields.rollbackFuncs[i])
+					t.Append(txFunc, tt.fields.rollbackFuncs[i])
 				}
 
-				if err := t.ExecuteFuncs(); err != nil {
+				if err := t.ExecuteAll(); err != nil {
 					t1.Error(err)
 					t1.Fail()
 				}
 
-				err := t.ExecuteFunc(tt.appendTx, tt.appendRB)
+				err := t.Do(tt.appendTx, tt.appendRB)
 				if (err != nil) != tt.wantErr {
 					t1.Errorf("ExecuteFunc() error = %v, wantErr %v", err, tt.wantErr)
 				}
@@ -336,7 +336,7 @@ func TestTx_ExecuteFunc(t1 *testing.T) {
 				if !tt.assertions(t1, a, b, c) {
 					t1.Fail()
 				}
-				t.AppendFunc(func() error { return nil }, tt.appendRB)
+				t.Append(func() error { return nil }, tt.appendRB)
 			},
 		)
 	}
@@ -524,10 +524,10 @@ func TestTx_ExecuteRetry(t1 *testing.T) {
 					if tt.fields.async {
 						wg.Add(1)
 					}
-					t.AppendFunc(txFunc, tt.fields.rollbackFuncs[i])
+					t.Append(txFunc, tt.fields.rollbackFuncs[i])
 				}
 
-				err := t.ExecuteFuncs()
+				err := t.ExecuteAll()
 				if (err != nil) != tt.wantErr {
 					t1.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
 				}
@@ -668,15 +668,15 @@ func TestTx_ExecuteFuncRetry(t1 *testing.T) {
 
 				defer t.rollback()
 				for i, txFunc := range tt.fields.txFuncs {
-					t.AppendFunc(txFunc, tt.fields.rollbackFuncs[i])
+					t.Append(txFunc, tt.fields.rollbackFuncs[i])
 				}
 
-				if err := t.ExecuteFuncs(); err != nil {
+				if err := t.ExecuteAll(); err != nil {
 					t1.Error(err)
 					t1.Fail()
 				}
 
-				err := t.ExecuteFunc(tt.appendTx, tt.appendRB)
+				err := t.Do(tt.appendTx, tt.appendRB)
 				if (err != nil) != tt.wantErr {
 					t1.Errorf("ExecuteFunc() error = %v, wantErr %v", err, tt.wantErr)
 				}
@@ -684,8 +684,92 @@ func TestTx_ExecuteFuncRetry(t1 *testing.T) {
 				if !tt.assertions(t1, a, b, c) {
 					t1.Fail()
 				}
-				t.AppendFunc(func() error { return nil }, tt.appendRB)
+				t.Append(func() error { return nil }, tt.appendRB)
 			},
 		)
 	}
 }
+
+func TestTx_AppendSaga(t1 *testing.T) {
+	tests := []struct {
+		name      string
+		childErr  error
+		parentErr error
+		wantErr   bool
+		wantLog   []string
+	}{
+		{
+			name:    "happypath",
+			wantErr: false,
+			wantLog: []string{"p1", "c1", "c2", "p2"},
+		},
+		{
+			name:     "child-fails",
+			childErr: errors.New("child error"),
+			wantErr:  true,
+			wantLog:  []string{"p1", "c1", "c2", "rollback c2", "rollback c1", "rollback p1"},
+		},
+		{
+			name:      "parent-fails-after-child",
+			parentErr: errors.New("parent error"),
+			wantErr:   true,
+			wantLog:   []string{"p1", "c1", "c2", "p2", "rollback p2", "rollback c2", "rollback c1", "rollback p1"},
+		},
+	}
+	for _, tt := range tests {
+		t1.Run(
+			tt.name, func(t1 *testing.T) {
+				var log []string
+				step := func(name string, err error) (UpdateFunc, CompensateFunc) {
+					return func() error {
+							log = append(log, name)
+							return err
+						}, func() error {
+							log = append(log, "rollback "+name)
+							return nil
+						}
+				}
+
+				child := NewSagaTx(false)
+				child.Append(step("c1", nil))
+				child.Append(step("c2", tt.childErr))
+
+				parent := NewSagaTx(false)
+				parent.Append(step("p1", nil))
+				parent.AppendSaga(child)
+				parent.Append(step("p2", tt.parentErr))
+
+				err := parent.ExecuteAll()
+				if (err != nil) != tt.wantErr {
+					t1.Errorf("ExecuteAll() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if fmt.Sprint(log) != fmt.Sprint(tt.wantLog) {
+					t1.Errorf("ExecuteAll() log = %v, want %v", log, tt.wantLog)
+				}
+			},
+		)
+	}
+}
+
+func TestTx_CompensationRetries(t *testing.T) {
+	failures := 2
+	tx := NewSagaTx(false)
+	tx.SetCompensationRetries(RetryOptions{MaxRetries: 3})
+	tx.Append(
+		func() error { return errors.New("out of stock") },
+		func() error {
+			if failures > 0 {
+				failures--
+				return errors.New("refund rejected")
+			}
+			return nil
+		},
+	)
+
+	if err := tx.ExecuteAll(); err == nil || err.Error() != "out of stock" {
+		t.Errorf("ExecuteAll() error = %v, want out of stock", err)
+	}
+	if failures != 0 {
+		t.Errorf("remaining failures = %d, want 0", failures)
+	}
+}
@@ -0,0 +1,69 @@
+package goTx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits between retries. Unless told otherwise,
+// retries and schedulers use the real clock; package gotxtest provides the fake
+// clock of a deterministic simulation.
+type Clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Scheduler runs the steps of async sagas and chains. The default scheduler
+// runs every step in its own goroutine; package gotxtest provides a
+// deterministic simulation.
+type Scheduler interface {
+	Clock
+	// Go runs the functions concurrently with the caller and returns a
+	// function that waits until they all returned.
+	Go(fns ...func()) (wait func())
+}
+
+// randomSource is implemented by clocks that provide the jitter of exponential
+// backoffs, so that simulated retries are reproducible.
+type randomSource interface {
+	Float64() float64
+}
+
+type goroutineScheduler struct{}
+
+func (goroutineScheduler) Now() time.Time {
+	return time.Now()
+}
+
+func (goroutineScheduler) Sleep(ctx context.Context, d time.Duration) error {
+	return sleepContext(ctx, d)
+}
+
+func (goroutineScheduler) Go(fns ...func()) func() {
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	return wg.Wait
+}
+
+func schedulerOrDefault(s Scheduler) Scheduler {
+	if s == nil {
+		return goroutineScheduler{}
+	}
+	return s
+}
+
+// withClock returns options that wait on the clock of s, unless they have a
+// clock of their own.
+func withClock(options RetryOptions, s Scheduler) RetryOptions {
+	if options.Clock == nil && s != nil {
+		options.Clock = s
+	}
+	return options
+}
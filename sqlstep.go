@@ -0,0 +1,98 @@
+package goTx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+func NewStatement(query string, args ...any) Statement {
+	return Statement{Query: query, Args: args}
+}
+
+// SQLUpdate returns the update and compensation of a SagaTx step that runs fn
+// in a database transaction started with opts. The transaction is committed if
+// fn succeeds and rolled back otherwise. The compensation executes the
+// compensating statements in a transaction of its own:
+//
+//	sagaTx.Append(SQLUpdate(db, &sql.TxOptions{Isolation: sql.LevelSerializable}, debit,
+//		NewStatement("UPDATE accounts SET balance = balance + ? WHERE id = ?", amount, from)))
+func SQLUpdate(db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error, compensation ...Statement) (UpdateFunc, CompensateFunc) {
+	update := TxStep(db, opts, func(_ context.Context, tx *sql.Tx) error { return fn(tx) })
+	compensate := StatementsStep(db, opts, compensation...)
+
+	return func() error { return update(context.Background()) },
+		func() error {
+			if compensate == nil {
+				return nil
+			}
+			return compensate(context.Background())
+		}
+}
+
+// SQLStep is the saga definition counterpart of SQLUpdate.
+func SQLStep(name string, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error, compensation ...Statement) Step {
+	return Step{
+		Name:       name,
+		Update:     TxStep(db, opts, fn),
+		Compensate: StatementsStep(db, opts, compensation...),
+	}
+}
+
+// TxStep returns a StepFunc that runs fn in a database transaction started with
+// opts, committing it if fn succeeds and rolling it back otherwise.
+func TxStep(db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) StepFunc {
+	return func(ctx context.Context) error {
+		return inTx(ctx, db, opts, fn)
+	}
+}
+
+// StatementsStep returns a StepFunc that executes stmts in order in a single
+// database transaction started with opts.
+func StatementsStep(db *sql.DB, opts *sql.TxOptions, stmts ...Statement) StepFunc {
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	return TxStep(db, opts, func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt.Query, stmt.Args...); err != nil {
+				return fmt.Errorf("exec %q: %w", stmt.Query, err)
+			}
+		}
+		return nil
+	})
+}
+
+func inTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	done := false
+	defer func() {
+		// fn panicked; roll back so that the connection is not leaked.
+		if !done {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = fn(ctx, tx)
+	done = true
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return joinErrors(err, fmt.Errorf("rollback transaction: %w", rerr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package goTx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const (
+	debitQuery  = "UPDATE accounts SET balance = balance - ? WHERE id = ?"
+	creditQuery = "UPDATE accounts SET balance = balance + ? WHERE id = ?"
+)
+
+func TestSQLUpdate(t *testing.T) {
+	errExec := errors.New("exec error")
+	tests := []struct {
+		name       string
+		failCredit bool
+		wantErr    bool
+		wantLog    []string
+	}{
+		{
+			name: "happypath",
+			wantLog: []string{
+				"begin", debitQuery + " [10 alice]", "commit",
+				"begin", creditQuery + " [10 bob]", "commit",
+			},
+		},
+		{
+			name:       "second-step-fails",
+			failCredit: true,
+			wantErr:    true,
+			wantLog: []string{
+				"begin", debitQuery + " [10 alice]", "commit",
+				"begin", "failed " + creditQuery, "rollback",
+				"begin", creditQuery + " [10 alice]", "INSERT INTO audit (event) VALUES (?) [refund]", "commit",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				db, fake := newFakeDB()
+				defer db.Close()
+
+				failures := 0
+				if tt.failCredit {
+					failures = 1
+				}
+				fake.execErr = func(query string) error {
+					if query == creditQuery && failures > 0 {
+						failures--
+						return errExec
+					}
+					return nil
+				}
+				exec := func(query, account string) func(tx *sql.Tx) error {
+					return func(tx *sql.Tx) error {
+						_, err := tx.Exec(query, 10, account)
+						return err
+					}
+				}
+
+				opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+				sagaTx := NewSagaTx(false)
+				sagaTx.Append(SQLUpdate(db, opts, exec(debitQuery, "alice"),
+					NewStatement(creditQuery, 10, "alice"),
+					NewStatement("INSERT INTO audit (event) VALUES (?)", "refund"),
+				))
+				sagaTx.Append(SQLUpdate(db, opts, exec(creditQuery, "bob")))
+
+				err := sagaTx.ExecuteAll()
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ExecuteAll() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if tt.wantErr && !errors.Is(err, errExec) {
+					t.Errorf("ExecuteAll() error = %v, want %v", err, errExec)
+				}
+				if got := fake.entries(); fmt.Sprint(got) != fmt.Sprint(tt.wantLog) {
+					t.Errorf("statements = %q, want %q", got, tt.wantLog)
+				}
+				for _, txOpts := range fake.txOpts {
+					if txOpts.Isolation != driver.IsolationLevel(sql.LevelSerializable) {
+						t.Errorf("isolation = %v, want %v", sql.IsolationLevel(txOpts.Isolation), sql.LevelSerializable)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestSQLStep(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	def, err := NewSagaBuilder("transfer").
+		AppendStep(SQLStep("debit", db, &sql.TxOptions{ReadOnly: false}, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, debitQuery, 10, "alice")
+			return err
+		}, NewStatement(creditQuery, 10, "alice"))).
+		AppendStep(SQLStep("credit", db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			return errors.New("account closed")
+		})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec, err := def.Execute(context.Background())
+	if err == nil {
+		t.Fatal("Execute() error = nil, want error")
+	}
+	want := []string{
+		"begin", debitQuery + " [10 alice]", "commit",
+		"begin", "rollback",
+		"begin", creditQuery + " [10 alice]", "commit",
+	}
+	if got := fake.entries(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+	if debit, _ := exec.Report().Step("debit"); debit.Status != StepCompensated {
+		t.Errorf("debit status = %v, want %v", debit.Status, StepCompensated)
+	}
+}
+
+func TestSQLUpdate_Panic(t *testing.T) {
+	db, fake := newFakeDB()
+	defer db.Close()
+
+	sagaTx := NewSagaTx(false)
+	sagaTx.Append(SQLUpdate(db, nil, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(debitQuery, 10, "alice"); err != nil {
+			return err
+		}
+		panic("boom")
+	}))
+
+	var perr *PanicError
+	if err := sagaTx.ExecuteAll(); !errors.As(err, &perr) {
+		t.Fatalf("ExecuteAll() error = %v, want PanicError", err)
+	}
+	want := []string{"begin", debitQuery + " [10 alice]", "rollback"}
+	if got := fake.entries(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("connections in use = %d, want 0", inUse)
+	}
+}
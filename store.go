@@ -0,0 +1,316 @@
+package goTx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrExecutionNotFound = errors.New("saga execution not found")
+
+// ExecutionStore persists the reports of saga executions. See
+// SagaBuilder.WithStore.
+type ExecutionStore interface {
+	Save(ctx context.Context, report *ExecutionReport) error
+	Load(ctx context.Context, id string) (*ExecutionReport, error)
+	List(ctx context.Context) ([]*ExecutionReport, error)
+}
+
+type CommandAction string
+
+const (
+	CommandResume     CommandAction = "resume"
+	CommandCompensate CommandAction = "compensate"
+	CommandSkip       CommandAction = "skip"
+)
+
+// Command is a request from an operator to act on a stored execution, for
+// instance one that got stuck because the process running it crashed or one
+// that is suspended.
+type Command struct {
+	ID          string        `json:"id"`
+	ExecutionID string        `json:"execution_id"`
+	Action      CommandAction `json:"action"`
+	// Step is the step to skip for CommandSkip.
+	Step        string    `json:"step,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	// Err is the error of the last attempt to apply the command, if it could
+	// not be applied.
+	Err string `json:"error,omitempty"`
+}
+
+// CommandStore queues commands until a process that knows the saga definitions
+// handles them with HandleCommands.
+type CommandStore interface {
+	AddCommand(ctx context.Context, cmd Command) error
+	Commands(ctx context.Context) ([]Command, error)
+	DeleteCommand(ctx context.Context, id string) error
+}
+
+// FileStore is an ExecutionStore, CommandStore and DeadLetterStore keeping one
+// JSON file per execution, command and dead letter in a directory:
+//
+//	<dir>/executions/<execution id>.json
+//	<dir>/commands/<command id>.json
+//	<dir>/deadletters/<dead letter id>.json
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{"executions", "commands", "deadletters"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Save(_ context.Context, report *ExecutionReport) error {
+	return writeJSONFile(s.path("executions", report.ID), report)
+}
+
+func (s *FileStore) Load(_ context.Context, id string) (*ExecutionReport, error) {
+	var report ExecutionReport
+	if err := readJSONFile(s.path("executions", id), &report); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %q", ErrExecutionNotFound, id)
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List returns the stored executions ordered by the time they started.
+func (s *FileStore) List(_ context.Context) ([]*ExecutionReport, error) {
+	var reports []*ExecutionReport
+	err := s.each("executions", func(path string) error {
+		var report ExecutionReport
+		if err := readJSONFile(path, &report); err != nil {
+			return err
+		}
+		reports = append(reports, &report)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].StartedAt.Before(reports[j].StartedAt)
+	})
+	return reports, nil
+}
+
+func (s *FileStore) AddCommand(_ context.Context, cmd Command) error {
+	if cmd.ID == "" {
+		cmd.ID = newID()
+	}
+	if cmd.RequestedAt.IsZero() {
+		cmd.RequestedAt = time.Now()
+	}
+	return writeJSONFile(s.path("commands", cmd.ID), cmd)
+}
+
+// Commands returns the queued commands in the order they were requested.
+func (s *FileStore) Commands(_ context.Context) ([]Command, error) {
+	var commands []Command
+	err := s.each("commands", func(path string) error {
+		var cmd Command
+		if err := readJSONFile(path, &cmd); err != nil {
+			return err
+		}
+		commands = append(commands, cmd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].RequestedAt.Before(commands[j].RequestedAt)
+	})
+	return commands, nil
+}
+
+func (s *FileStore) DeleteCommand(_ context.Context, id string) error {
+	err := os.Remove(s.path("commands", id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) AddDeadLetter(_ context.Context, dl DeadLetter) error {
+	return writeJSONFile(s.path("deadletters", dl.ID), dl)
+}
+
+// DeadLetters returns the dead letters in the order they were created.
+func (s *FileStore) DeadLetters(_ context.Context) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	err := s.each("deadletters", func(path string) error {
+		var dl DeadLetter
+		if err := readJSONFile(path, &dl); err != nil {
+			return err
+		}
+		deadLetters = append(deadLetters, dl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortDeadLetters(deadLetters)
+	return deadLetters, nil
+}
+
+func (s *FileStore) DeleteDeadLetter(_ context.Context, id string) error {
+	err := os.Remove(s.path("deadletters", id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) path(sub, id string) string {
+	return filepath.Join(s.dir, sub, url.PathEscape(id)+".json")
+}
+
+func (s *FileStore) each(sub string, fn func(path string) error) error {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if err := fn(filepath.Join(s.dir, sub, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONFile replaces the file at path atomically, so that readers never see
+// a partially written file.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// HandleCommands applies the queued commands to the stored executions of the
+// given sagas and removes them from the queue. Commands for executions of
+// other sagas are left in the queue for another process. A command that could
+// not be applied, for instance because the execution could not be loaded or
+// restored or its report could not be saved, is kept with its error in
+// Command.Err and retried by the next call. A command that can never be
+// applied, because its action is unknown or the step to skip cannot be
+// skipped, is removed. It returns the errors of the
+// commands that could not be applied and the errors the executions returned,
+// such as the error that caused an execution to be compensated, joined.
+//
+// Resuming or compensating an execution that is still running in another
+// process runs its steps twice; commands are meant for executions the operator
+// knows to be stuck.
+func HandleCommands(ctx context.Context, store ExecutionStore, commands CommandStore, defs ...*SagaDefinition) error {
+	byName := make(map[string]*SagaDefinition, len(defs))
+	for _, def := range defs {
+		byName[def.name] = def
+	}
+
+	queued, err := commands.Commands(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cmd := range queued {
+		var done bool
+		report, err := store.Load(ctx, cmd.ExecutionID)
+		if err == nil {
+			def, ok := byName[report.Saga]
+			if !ok {
+				continue
+			}
+			done, err = handleCommand(ctx, def, report, cmd)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("command %s: %s execution %q: %w", cmd.ID, cmd.Action, cmd.ExecutionID, err))
+		}
+
+		if !done {
+			cmd.Err = err.Error()
+			if err := commands.AddCommand(ctx, cmd); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := commands.DeleteCommand(ctx, cmd.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// handleCommand applies cmd to the execution of report and tells whether the
+// command is done with: either its action ran and the resulting report was
+// saved, even if the execution returned an error, or it can never be applied.
+func handleCommand(ctx context.Context, def *SagaDefinition, report *ExecutionReport, cmd Command) (done bool, err error) {
+	exec, err := def.RestoreExecution(report)
+	if err != nil {
+		return false, err
+	}
+
+	switch cmd.Action {
+	case CommandResume:
+		err = exec.Resume(ctx)
+	case CommandCompensate:
+		err = exec.ForceCompensate(ctx, "compensation requested by operator")
+	case CommandSkip:
+		if err := exec.Skip(cmd.Step); err != nil {
+			return true, err
+		}
+		err = exec.Resume(ctx)
+	default:
+		return true, fmt.Errorf("unknown action %q", cmd.Action)
+	}
+
+	var serr *saveError
+	return !errors.As(err, &serr), err
+}
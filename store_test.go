@@ -0,0 +1,397 @@
+package goTx
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// crash runs exec the way a process that dies while a step is running would:
+// the step that calls crashStep never returns.
+func crash(t *testing.T, exec *SagaExecution) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exec.Execute(context.Background())
+	}()
+	<-done
+}
+
+func crashStep(name string) Step {
+	return Step{
+		Name:   name,
+		Update: func(context.Context) error { runtime.Goexit(); return nil },
+	}
+}
+
+func TestHandleCommands(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    CommandAction
+		nested    bool
+		wantState ExecutionState
+		wantLog   string
+	}{
+		{
+			name:      "resume",
+			action:    CommandResume,
+			wantState: ExecutionSucceeded,
+			wantLog:   "[a b c]",
+		},
+		{
+			name:      "compensate",
+			action:    CommandCompensate,
+			wantState: ExecutionCompensated,
+			wantLog:   "[a rollback a]",
+		},
+		{
+			name:      "resume nested saga",
+			action:    CommandResume,
+			nested:    true,
+			wantState: ExecutionSucceeded,
+			wantLog:   "[a b c]",
+		},
+		{
+			name:      "compensate nested saga",
+			action:    CommandCompensate,
+			nested:    true,
+			wantState: ExecutionCompensated,
+			wantLog:   "[a rollback a]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				log := &stepLog{}
+				build := func(b Step) *SagaDefinition {
+					builder := NewSagaBuilder("order").WithStore(store)
+					if tt.nested {
+						child, err := NewSagaBuilder("child").
+							AppendStep(loggedStep(log, "a", nil)).
+							AppendStep(b).
+							Build()
+						if err != nil {
+							t.Fatal(err)
+						}
+						builder.AppendSaga("child", child)
+					} else {
+						builder.AppendStep(loggedStep(log, "a", nil)).AppendStep(b)
+					}
+					def, err := builder.AppendStep(loggedStep(log, "c", nil)).Build()
+					if err != nil {
+						t.Fatal(err)
+					}
+					return def
+				}
+
+				exec := build(crashStep("b")).NewExecutionWithID("order-1")
+				crash(t, exec)
+
+				report, err := store.Load(ctx, "order-1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.State != ExecutionRunning {
+					t.Fatalf("stored state = %s, want running", report.State)
+				}
+
+				if err := store.AddCommand(ctx, Command{ExecutionID: "order-1", Action: tt.action}); err != nil {
+					t.Fatal(err)
+				}
+				// The restarted process knows the saga, with the bug in step b fixed.
+				def := build(loggedStep(log, "b", nil))
+				if err := HandleCommands(ctx, store, store, def); err != nil {
+					t.Fatal(err)
+				}
+
+				report, err = store.Load(ctx, "order-1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.State != tt.wantState {
+					t.Errorf("stored state = %s, want %s", report.State, tt.wantState)
+				}
+				if got := log.String(); got != tt.wantLog {
+					t.Errorf("log = %q, want %q", got, tt.wantLog)
+				}
+
+				commands, err := store.Commands(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(commands) != 0 {
+					t.Errorf("commands = %v, want none", commands)
+				}
+			},
+		)
+	}
+}
+
+func TestHandleCommands_UnknownSaga(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := NewSagaBuilder("order").WithStore(store).AppendStep(crashStep("a")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash(t, def.NewExecutionWithID("order-1"))
+
+	if err := store.AddCommand(ctx, Command{ExecutionID: "order-1", Action: CommandResume}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddCommand(ctx, Command{ExecutionID: "missing", Action: CommandResume}); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewSagaBuilder("payment").Append("a", func() error { return nil }, nil).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := HandleCommands(ctx, store, store, other); !errors.Is(err, ErrExecutionNotFound) {
+		t.Errorf("HandleCommands() error = %v, want %v", err, ErrExecutionNotFound)
+	}
+
+	commands, err := store.Commands(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 || commands[0].ExecutionID != "order-1" {
+		t.Errorf("commands = %v, want the command for order-1 and the missing execution kept", commands)
+	}
+	if len(commands) == 2 && !strings.Contains(commands[1].Err, "not found") {
+		t.Errorf("missing execution command error = %q, want not found", commands[1].Err)
+	}
+}
+
+func TestHandleCommands_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     Command
+		wantErr string
+	}{
+		{
+			name:    "unknown action",
+			cmd:     Command{ExecutionID: "order-1", Action: "restart"},
+			wantErr: `unknown action "restart"`,
+		},
+		{
+			name:    "unknown step",
+			cmd:     Command{ExecutionID: "order-1", Action: CommandSkip, Step: "ship"},
+			wantErr: "unknown step",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				def, err := NewSagaBuilder("order").WithStore(store).AppendStep(crashStep("a")).Build()
+				if err != nil {
+					t.Fatal(err)
+				}
+				crash(t, def.NewExecutionWithID("order-1"))
+				if err := store.AddCommand(ctx, tt.cmd); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := HandleCommands(ctx, store, store, def); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("HandleCommands() error = %v, want %q", err, tt.wantErr)
+				}
+				if commands, _ := store.Commands(ctx); len(commands) != 0 {
+					t.Errorf("commands = %+v, want the invalid command removed", commands)
+				}
+			},
+		)
+	}
+}
+
+func TestSagaExecution_Resume(t *testing.T) {
+	def, err := NewSagaBuilder("order").Append("a", func() error { return nil }, nil).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec, err := def.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Resume(context.Background()); err == nil {
+		t.Error("Resume() of succeeded execution error = nil, want error")
+	}
+
+	restored, err := def.RestoreExecution(exec.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Resume(context.Background()); err == nil {
+		t.Error("Resume() of restored succeeded execution error = nil, want error")
+	}
+
+	other, err := NewSagaBuilder("payment").Append("a", func() error { return nil }, nil).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.RestoreExecution(exec.Report()); err == nil {
+		t.Error("RestoreExecution() of other saga error = nil, want error")
+	}
+}
+
+func TestHandleCommands_SkipSuspendedCompensation(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := &stepLog{}
+	refund := loggedStep(log, "refund", nil)
+	refund.Compensate = func(context.Context) error { return errors.New("refund rejected") }
+	def, err := NewSagaBuilder("order").
+		WithStore(store).
+		WithSuspendOnCompensationFailure().
+		AppendStep(loggedStep(log, "reserve", nil)).
+		AppendStep(refund).
+		AppendStep(loggedStep(log, "ship", errors.New("out of stock"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := def.NewExecutionWithID("order-1").Execute(ctx); !errors.Is(err, ErrExecutionSuspended) {
+		t.Fatalf("Execute() error = %v, want %v", err, ErrExecutionSuspended)
+	}
+
+	report, err := store.Load(ctx, "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.State != ExecutionSuspended || report.SuspendReason == "" {
+		t.Fatalf("stored state = %s (%q), want suspended with a reason", report.State, report.SuspendReason)
+	}
+
+	if err := store.AddCommand(ctx, Command{ExecutionID: "order-1", Action: CommandSkip, Step: "refund"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := HandleCommands(ctx, store, store, def); err == nil || !strings.Contains(err.Error(), "out of stock") {
+		t.Errorf("HandleCommands() error = %v, want the error of step ship", err)
+	}
+
+	report, err = store.Load(ctx, "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.State != ExecutionCompensated {
+		t.Errorf("stored state = %s, want compensated", report.State)
+	}
+	if got := report.StepsWithStatus(StepSkipped); len(got) != 1 || got[0] != "refund" {
+		t.Errorf("skipped steps = %v, want [refund]", got)
+	}
+	if got, want := log.String(), "[reserve refund ship rollback reserve]"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+	if commands, _ := store.Commands(ctx); len(commands) != 0 {
+		t.Errorf("commands = %v, want the applied command removed", commands)
+	}
+}
+
+// unsavableStore is a FileStore whose Save fails.
+type unsavableStore struct {
+	*FileStore
+}
+
+func (s unsavableStore) Save(context.Context, *ExecutionReport) error {
+	return errors.New("disk full")
+}
+
+func TestHandleCommands_NotApplied(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(store *FileStore, log *stepLog) (*SagaDefinition, error)
+		wantErr string
+	}{
+		{
+			name: "restore fails",
+			build: func(store *FileStore, log *stepLog) (*SagaDefinition, error) {
+				return NewSagaBuilder("order").WithStore(store).AppendStep(loggedStep(log, "b", nil)).Build()
+			},
+			wantErr: "report has 2 steps, saga has 1",
+		},
+		{
+			name: "save fails",
+			build: func(store *FileStore, log *stepLog) (*SagaDefinition, error) {
+				return NewSagaBuilder("order").
+					WithStore(unsavableStore{store}).
+					AppendStep(loggedStep(log, "a", nil)).
+					AppendStep(loggedStep(log, "b", nil)).
+					Build()
+			},
+			wantErr: "disk full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				store, err := NewFileStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				log := &stepLog{}
+				build := func(b Step) *SagaDefinition {
+					def, err := NewSagaBuilder("order").WithStore(store).AppendStep(loggedStep(log, "a", nil)).AppendStep(b).Build()
+					if err != nil {
+						t.Fatal(err)
+					}
+					return def
+				}
+				crash(t, build(crashStep("b")).NewExecutionWithID("order-1"))
+				if err := store.AddCommand(ctx, Command{ExecutionID: "order-1", Action: CommandResume}); err != nil {
+					t.Fatal(err)
+				}
+
+				def, err := tt.build(store, log)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := HandleCommands(ctx, store, store, def); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("HandleCommands() error = %v, want %q", err, tt.wantErr)
+				}
+				commands, err := store.Commands(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(commands) != 1 || !strings.Contains(commands[0].Err, tt.wantErr) {
+					t.Fatalf("commands = %+v, want the command kept with its error", commands)
+				}
+
+				// The next call retries the command.
+				if err := HandleCommands(ctx, store, store, build(loggedStep(log, "b", nil))); err != nil {
+					t.Fatalf("HandleCommands() retry error = %v", err)
+				}
+				if commands, _ := store.Commands(ctx); len(commands) != 0 {
+					t.Errorf("commands = %+v, want none", commands)
+				}
+				if report, _ := store.Load(ctx, "order-1"); report.State != ExecutionSucceeded {
+					t.Errorf("stored state = %s, want succeeded", report.State)
+				}
+			},
+		)
+	}
+}